@@ -18,47 +18,139 @@
 package tesseract
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"strconv"
-	//"github.com/ethereum/go-ethereum/log"
+	"math"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 // Actions are authenticated requests to modify the game state.
 // Most actions originate from users, where we consider them
-// authenticated post user account signature verification.
+// authenticated post user account signature verification (see
+// DecodeActionRequest).
 // Actions can also originate from the game engine itself;
 // such actions are always considered authenticated.
 type Action interface {
+	Entity() Id
 	Execute() error
 }
 
+// ActionStatus is the outcome of an action at some stage of handling.
+type ActionStatus uint8
+
+const (
+	// ActionAccepted actions passed decoding and are queued for the engine.
+	ActionAccepted ActionStatus = iota
+	// ActionRejected actions failed decoding or execution; see Reason.
+	ActionRejected
+	// ActionApplied actions were executed by the engine in Frame.
+	ActionApplied
+)
+
+var actionStatuses = map[ActionStatus]string{
+	ActionAccepted: "accepted",
+	ActionRejected: "rejected",
+	ActionApplied:  "applied",
+}
+
+func (s ActionStatus) String() string {
+	return actionStatuses[s]
+}
+
+func (s ActionStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// ActionResult reports the handling of an action back to its origin.
+// Id is the client chosen id of the action envelope.
+type ActionResult struct {
+	Id     uint64       `json:"id"`
+	Status ActionStatus `json:"status"`
+	Reason string       `json:"reason,omitempty"`
+	Frame  uint64       `json:"frame,omitempty"`
+
+	// Data is action specific output of applied actions, e.g. the id of
+	// a scheduled timer
+	Data interface{} `json:"data,omitempty"`
+}
+
+// resultAction is implemented by actions with output for ActionResult.Data
+type resultAction interface {
+	result() interface{}
+}
+
+// originAction is implemented by actions that need to know their request,
+// e.g. to act on behalf of the signer later.  The engine calls setOrigin
+// after authorizing and before executing the action.
+type originAction interface {
+	setOrigin(req *ActionRequest)
+}
+
+// ActionRequest is an action queued for the game engine, along with its
+// signer and where to route its results.  Results may be nil, e.g. for
+// engine originated actions.
+type ActionRequest struct {
+	Id      uint64
+	Action  Action
+	Results chan<- *ActionResult
+
+	Signer common.Address
+	Nonce  uint64
+
+	// engine is set for actions originating from the engine itself
+	engine bool
+}
+
+// report delivers a result to the origin of the request.  Results are
+// dropped rather than stalling the engine if the origin is not keeping up.
+func (r *ActionRequest) report(status ActionStatus, reason string, frame uint64) {
+	r.reportResult(&ActionResult{r.Id, status, reason, frame, nil})
+}
+
+func (r *ActionRequest) reportResult(result *ActionResult) {
+	if r.Results == nil {
+		return
+	}
+	select {
+	case r.Results <- result:
+	default:
+		log.Warn("action result dropped", "id", r.Id, "status", result.Status)
+	}
+}
+
+// HandleAction decodes an action and queues it for execution by the
+// game engine.  The results of the action - accepted, rejected or
+// applied - are sent on results.
+//
 // The input to this function is raw bytes from other layers, e.g.
 // the binary payload of a WebSocket message.  As such these bytes have
-// not yet been validated, and may be malicious.
+// not yet been validated, and may be malicious.  See DecodeAction for the
+// validation performed.  The action must be signed, and its nonce is
+// used up once it passes validation.  A rejected action is reported on results and
+// its error also returned; it does not affect other actions.
 // TODO: refactor and document security assumptions and input validation
 // in diff layers.
-// TODO: for dev/test we use a simple JSON schema
-func HandleAction(j map[string]interface{}) error {
-	e, err := strconv.ParseUint(j["entity"].(string), 10, 64)
+func HandleAction(msg []byte, results chan<- *ActionResult) error {
+	if GE == nil {
+		return errors.New("game engine not running")
+	}
+	req, err := DecodeActionRequest(msg)
+	req.Results = results
+	if err == nil {
+		err = S.Nonces.Use(req.Signer, req.Nonce)
+	}
 	if err != nil {
+		GE.metrics.actionRejected()
+		req.report(ActionRejected, err.Error(), 0)
 		return err
 	}
-	params := j["params"].(map[string]interface{})
-	duration := params["duration"].(float64)
-
-	var ar Action
-	if j["action"] == "rotate" {
-		torque := params["force"].(map[string]interface{})
-		x := torque["x"].(float64)
-		y := torque["y"].(float64)
-		z := torque["z"].(float64)
-		ar = &ActionRotate{Id(e), &V3{x, y, z}, duration}
-	} else {
-		f := params["force"].(float64)
-		ar = &ActionEngineThrust{Id(e), f, duration}
-	}
-	GE.actionChan <- ar
 
+	// reported before queueing, as the engine may apply the action at once
+	req.report(ActionAccepted, "", 0)
+	GE.actionChan <- req
 	return nil
 }
 
@@ -68,11 +160,22 @@ type ActionRotate struct {
 	duration float64
 }
 
+func (a *ActionRotate) Entity() Id { return a.entity }
+
 func (a *ActionRotate) Execute() error {
+	if S.ShipClass[a.entity] == nil {
+		return fmt.Errorf("entity %v has no ship class", a.entity)
+	}
 	max := S.ShipClass[a.entity].CMGTorqueCap()
-	if a.t.X > max.X || a.t.Y > max.Y || a.t.Z > max.Z {
+	if math.Abs(a.t.X) > max.X || math.Abs(a.t.Y) > max.Y || math.Abs(a.t.Z) > max.Z {
 		return fmt.Errorf("torque %v %v %v larger than CMG cap %v %v %v", a.t.X, a.t.Y, a.t.Z, max.X, max.Y, max.Z)
 	}
+	if c := S.CMG[a.entity]; c != nil {
+		err := c.CanDeliver(a.t, a.duration, S.ShipClass[a.entity].CMGMomentumCap())
+		if err != nil {
+			return err
+		}
+	}
 
 	S.AddForceGen(a.entity, &TurnForceGen{a.t, a.duration})
 	return nil
@@ -84,7 +187,194 @@ type ActionEngineThrust struct {
 	duration float64
 }
 
+func (a *ActionEngineThrust) Entity() Id { return a.entity }
+
 func (a *ActionEngineThrust) Execute() error {
-	S.AddForceGen(a.entity, &ThrustForceGen{a.thrust, a.duration})
+	engine := S.Engine[a.entity]
+	if engine == nil {
+		return fmt.Errorf("entity %v has no engine", a.entity)
+	}
+	if math.IsNaN(a.duration) || a.duration < 0 {
+		return fmt.Errorf("invalid thrust duration %v", a.duration)
+	}
+	for _, fg := range S.ForceGens[a.entity] {
+		if b, ok := fg.(*BurnForceGen); ok && !b.IsExpired() {
+			return fmt.Errorf("engine of entity %v in use by maneuver", a.entity)
+		}
+	}
+	if err := engine.SetThrust(a.thrust); err != nil {
+		return err
+	}
+
+	// a running burn is extended / cut short rather than doubled
+	for _, fg := range S.ForceGens[a.entity] {
+		if g, ok := fg.(*EngineForceGen); ok && !g.IsExpired() {
+			g.timeLeft = a.duration
+			return nil
+		}
+	}
+	S.AddForceGen(a.entity, &EngineForceGen{timeLeft: a.duration})
+	return nil
+}
+
+type ActionEngineGimbal struct {
+	entity     Id
+	pitch, yaw float64
+}
+
+func (a *ActionEngineGimbal) Entity() Id { return a.entity }
+
+func (a *ActionEngineGimbal) Execute() error {
+	engine := S.Engine[a.entity]
+	if engine == nil {
+		return fmt.Errorf("entity %v has no engine", a.entity)
+	}
+	return engine.SetGimbal(a.pitch, a.yaw)
+}
+
+// ActionAttitude engages the attitude autopilot in the given mode,
+// replacing any active autopilot.  AttitudeOff releases the ship.
+type ActionAttitude struct {
+	entity Id
+	mode   AttitudeMode
+	target Id
+}
+
+func (a *ActionAttitude) Entity() Id { return a.entity }
+
+func (a *ActionAttitude) Execute() error {
+	if S.ShipClass[a.entity] == nil || S.Rot[a.entity] == nil {
+		return fmt.Errorf("entity %v cannot rotate", a.entity)
+	}
+	if a.mode == AttitudeTarget && S.EntFrames[a.target] == nil {
+		return fmt.Errorf("unknown attitude target %v", a.target)
+	}
+
+	cancelAttitude(a.entity)
+	if a.mode != AttitudeOff {
+		S.AddForceGen(a.entity, &AttitudeForceGen{Mode: a.mode, Target: a.target})
+	}
+	return nil
+}
+
+// ActionDesaturate dumps the momentum stored in the ship's CMG.
+type ActionDesaturate struct {
+	entity Id
+	method DesatMethod
+}
+
+func (a *ActionDesaturate) Entity() Id { return a.entity }
+
+func (a *ActionDesaturate) Execute() error {
+	if S.CMG[a.entity] == nil {
+		return fmt.Errorf("entity %v has no CMG", a.entity)
+	}
+	if a.method == DesatMagnetic && magneticField(a.entity) == 0 {
+		return fmt.Errorf("no magnetic field at entity %v", a.entity)
+	}
+
+	S.AddForceGen(a.entity, &DesatForceGen{Method: a.method})
+	return nil
+}
+
+// ActionSetDelegate grants or revokes control of the entity to another
+// account.  Only the owner may execute it.
+type ActionSetDelegate struct {
+	entity   Id
+	delegate common.Address
+	allow    bool
+}
+
+func (a *ActionSetDelegate) Entity() Id { return a.entity }
+func (a *ActionSetDelegate) ownerOnly() {}
+
+func (a *ActionSetDelegate) Execute() error {
+	S.SetDelegate(a.entity, a.delegate, a.allow)
+	return nil
+}
+
+// ActionTransferOwnership transfers the entity to another account,
+// revoking all delegates.  Only the owner may execute it.
+type ActionTransferOwnership struct {
+	entity Id
+	to     common.Address
+}
+
+func (a *ActionTransferOwnership) Entity() Id { return a.entity }
+func (a *ActionTransferOwnership) ownerOnly() {}
+
+func (a *ActionTransferOwnership) Execute() error {
+	if a.to == (common.Address{}) {
+		return errors.New("cannot transfer to the zero address")
+	}
+	S.SetOwner(a.entity, a.to)
+	return nil
+}
+
+// ActionSchedule schedules an action on the entity for a future world time
+// or the next orbital event of the entity.  The scheduled action is
+// authorized again, for the signer of this action, when its timer fires.
+type ActionSchedule struct {
+	entity Id
+	at     float64 // world time, if no event
+	event  string
+
+	env    *ActionEnvelope // scheduled action
+	action Action
+
+	origin *ActionRequest
+	timer  Id
+}
+
+func (a *ActionSchedule) Entity() Id                   { return a.entity }
+func (a *ActionSchedule) setOrigin(req *ActionRequest) { a.origin = req }
+func (a *ActionSchedule) result() interface{}          { return a.timer }
+
+func (a *ActionSchedule) Execute() error {
+	at := a.at
+	if a.event != "" {
+		dt, err := timeToOrbitalEvent(a.entity, a.event)
+		if err != nil {
+			return err
+		}
+		at = S.WorldTime + dt
+	}
+	if at < S.WorldTime {
+		return fmt.Errorf("world time %v already passed", at)
+	}
+	if len(S.Timers.ForEntity(a.entity)) >= maxTimersPerEntity {
+		return fmt.Errorf("entity %v has too many timers", a.entity)
+	}
+
+	t := &Timer{
+		Entity:   a.entity,
+		At:       at,
+		Event:    a.event,
+		Envelope: a.env,
+		action:   a.action,
+	}
+	if a.origin != nil {
+		t.Signer = a.origin.Signer
+		t.Envelope.Id = a.origin.Id
+		t.results = a.origin.Results
+	}
+	a.timer = S.Timers.Add(t)
+	return nil
+}
+
+// ActionCancelTimer cancels a pending timer of the entity.
+type ActionCancelTimer struct {
+	entity Id
+	timer  Id
+}
+
+func (a *ActionCancelTimer) Entity() Id { return a.entity }
+
+func (a *ActionCancelTimer) Execute() error {
+	t := S.Timers.Get(a.timer)
+	if t == nil || t.Entity != a.entity {
+		return fmt.Errorf("entity %v has no timer %v", a.entity, a.timer)
+	}
+	S.Timers.Cancel(a.timer)
 	return nil
 }
@@ -0,0 +1,567 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Actions are sent by clients as JSON encoded envelopes:
+//
+// {
+//   "version": 1,
+//   "id": 7,
+//   "action": "rotate",
+//   "entity": "42",
+//   "params": {"force": {"x": 0, "y": 1000, "z": 0}, "duration": 2.5},
+//   "signer": "0x9a3e41bf84e4e5e4f0b06f2f34a52d3e9ec1d2b1",
+//   "nonce": 12,
+//   "signature": "0x..."
+// }
+//
+// User actions must be signed by the user's account key; see
+// crypto.go for the signing hash and nonce rules.
+//
+// The optional id is chosen by the client and echoed in the ActionResults
+// of the action.  The action field selects the decoder for params from actionDecoders.
+// Decoding is strict: unknown fields, missing required fields, trailing
+// data and out of range numbers are all rejected with an error naming
+// the offending field.
+//
+// TODO: replace JSON with a compact binary encoding
+
+const actionVersion = 1
+
+type ActionEnvelope struct {
+	Version uint32          `json:"version"`
+	Id      uint64          `json:"id,omitempty"`
+	Action  string          `json:"action"`
+	Entity  string          `json:"entity"`
+	Params  json.RawMessage `json:"params"`
+
+	Signer    string `json:"signer,omitempty"`
+	Nonce     uint64 `json:"nonce,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// FieldError is returned for an action field failing validation.
+type FieldError struct {
+	Field string
+	Msg   string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("action field %s: %s", e.Field, e.Msg)
+}
+
+type actionDecoder func(e Id, params json.RawMessage) (Action, error)
+
+// actionDecoders is the registry of action types
+var actionDecoders = map[string]actionDecoder{
+	"rotate":     decodeActionRotate,
+	"thrust":     decodeActionEngineThrust,
+	"gimbal":     decodeActionEngineGimbal,
+	"attitude":   decodeActionAttitude,
+	"desaturate": decodeActionDesaturate,
+	"delegate":   decodeActionSetDelegate,
+	"transfer":   decodeActionTransferOwnership,
+	"cancel":     decodeActionCancelTimer,
+	"maneuver":   decodeActionManeuver,
+	"burn":       decodeActionBurn,
+	"hyperdrive": decodeActionHyperdrive,
+	"dropout":    decodeActionHyperdriveExit,
+	"interdict":  decodeActionInterdict,
+}
+
+func init() {
+	// registered here as it decodes the scheduled action via the registry
+	actionDecoders["schedule"] = decodeActionSchedule
+}
+
+// DecodeAction decodes and validates an action envelope.  The envelope
+// signature, if any, is not verified; see DecodeActionRequest.
+func DecodeAction(msg []byte) (Action, error) {
+	env := new(ActionEnvelope)
+	if err := decodeStrict(msg, env, ""); err != nil {
+		return nil, err
+	}
+	return env.decode()
+}
+
+// DecodeActionRequest decodes and validates a signed action envelope into
+// a request.  The returned request is never nil; on error it holds the
+// envelope id, if the envelope itself could be decoded.
+//
+// The nonce is not checked here, as it must only be used once the
+// action is certain to be queued.
+func DecodeActionRequest(msg []byte) (*ActionRequest, error) {
+	env := new(ActionEnvelope)
+	if err := decodeStrict(msg, env, ""); err != nil {
+		return &ActionRequest{}, err
+	}
+	req := &ActionRequest{Id: env.Id, Nonce: env.Nonce}
+	a, err := env.decode()
+	if err != nil {
+		return req, err
+	}
+	req.Signer, err = env.VerifySignature()
+	if err != nil {
+		return req, err
+	}
+	req.Action = a
+	return req, nil
+}
+
+func (env *ActionEnvelope) decode() (Action, error) {
+
+	if env.Version != actionVersion {
+		return nil, &FieldError{"version", fmt.Sprintf("unsupported version %d", env.Version)}
+	}
+	decode, ok := actionDecoders[env.Action]
+	if !ok {
+		return nil, &FieldError{"action", fmt.Sprintf("unknown action %q", env.Action)}
+	}
+	e, err := decodeId("entity", env.Entity)
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Params) == 0 {
+		return nil, &FieldError{"params", "required"}
+	}
+	return decode(e, env.Params)
+}
+
+// decodeStrict decodes a single JSON value into v, rejecting unknown
+// fields and trailing data.  Field names in errors are prefixed with
+// prefix.
+func decodeStrict(data []byte, v interface{}, prefix string) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		if te, ok := err.(*json.UnmarshalTypeError); ok {
+			msg := fmt.Sprintf("expected %v, got JSON %s", te.Type, te.Value)
+			if te.Field == "" {
+				return fmt.Errorf("invalid action: %s", msg)
+			}
+			return &FieldError{prefix + te.Field, msg}
+		}
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after action")
+	}
+	return nil
+}
+
+func decodeId(field, s string) (Id, error) {
+	if s == "" {
+		return 0, &FieldError{field, "required"}
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || id == 0 {
+		return 0, &FieldError{field, fmt.Sprintf("invalid entity id %q", s)}
+	}
+	return Id(id), nil
+}
+
+func decodeAddress(field, s string) (common.Address, error) {
+	if s == "" {
+		return common.Address{}, &FieldError{field, "required"}
+	}
+	if !common.IsHexAddress(s) {
+		return common.Address{}, &FieldError{field, fmt.Sprintf("invalid address %q", s)}
+	}
+	return common.HexToAddress(s), nil
+}
+
+func checkFinite(field string, f *float64) error {
+	if f == nil {
+		return &FieldError{field, "required"}
+	}
+	if math.IsNaN(*f) || math.IsInf(*f, 0) {
+		return &FieldError{field, "must be a finite number"}
+	}
+	return nil
+}
+
+func checkDuration(field string, f *float64) error {
+	if err := checkFinite(field, f); err != nil {
+		return err
+	}
+	if *f <= 0 || *f > maxActionDuration {
+		return &FieldError{field, fmt.Sprintf("must be in (0, %v] seconds", maxActionDuration)}
+	}
+	return nil
+}
+
+func checkV3(field string, v *V3) error {
+	if v == nil {
+		return &FieldError{field, "required"}
+	}
+	for _, c := range []struct {
+		n string
+		f float64
+	}{{"x", v.X}, {"y", v.Y}, {"z", v.Z}} {
+		if err := checkFinite(field+"."+c.n, &c.f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Action Decoders
+func decodeActionRotate(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		Force    *V3      `json:"force"`
+		Duration *float64 `json:"duration"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	if err := checkV3("params.force", p.Force); err != nil {
+		return nil, err
+	}
+	if err := checkDuration("params.duration", p.Duration); err != nil {
+		return nil, err
+	}
+	return &ActionRotate{e, p.Force, *p.Duration}, nil
+}
+
+func decodeActionEngineThrust(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		Force    *float64 `json:"force"`
+		Duration *float64 `json:"duration"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	if err := checkFinite("params.force", p.Force); err != nil {
+		return nil, err
+	}
+	if *p.Force < 0 {
+		return nil, &FieldError{"params.force", "must not be negative"}
+	}
+	if err := checkDuration("params.duration", p.Duration); err != nil {
+		return nil, err
+	}
+	return &ActionEngineThrust{e, *p.Force, *p.Duration}, nil
+}
+
+func decodeActionEngineGimbal(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		Pitch *float64 `json:"pitch"`
+		Yaw   *float64 `json:"yaw"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	if err := checkFinite("params.pitch", p.Pitch); err != nil {
+		return nil, err
+	}
+	if err := checkFinite("params.yaw", p.Yaw); err != nil {
+		return nil, err
+	}
+	return &ActionEngineGimbal{e, *p.Pitch, *p.Yaw}, nil
+}
+
+func decodeActionAttitude(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		Mode   string `json:"mode"`
+		Target string `json:"target"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	mode, ok := attitudeModes[p.Mode]
+	if !ok {
+		return nil, &FieldError{"params.mode", fmt.Sprintf("unknown attitude mode %q", p.Mode)}
+	}
+
+	var target Id
+	if mode == AttitudeTarget {
+		t, err := decodeId("params.target", p.Target)
+		if err != nil {
+			return nil, err
+		}
+		target = t
+	} else if p.Target != "" {
+		return nil, &FieldError{"params.target", "only valid with mode target"}
+	}
+	return &ActionAttitude{e, mode, target}, nil
+}
+
+func decodeActionDesaturate(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		Method string `json:"method"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	method, ok := desatMethods[p.Method]
+	if !ok {
+		return nil, &FieldError{"params.method", fmt.Sprintf("unknown desaturation method %q", p.Method)}
+	}
+	return &ActionDesaturate{e, method}, nil
+}
+
+func decodeActionSetDelegate(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		Delegate string `json:"delegate"`
+		Allow    *bool  `json:"allow"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	d, err := decodeAddress("params.delegate", p.Delegate)
+	if err != nil {
+		return nil, err
+	}
+	if p.Allow == nil {
+		return nil, &FieldError{"params.allow", "required"}
+	}
+	return &ActionSetDelegate{e, d, *p.Allow}, nil
+}
+
+func decodeActionTransferOwnership(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		To string `json:"to"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	to, err := decodeAddress("params.to", p.To)
+	if err != nil {
+		return nil, err
+	}
+	return &ActionTransferOwnership{e, to}, nil
+}
+
+func decodeActionSchedule(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		At     *float64 `json:"at"`
+		Event  string   `json:"event"`
+		Action *struct {
+			Action string          `json:"action"`
+			Params json.RawMessage `json:"params"`
+		} `json:"action"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+
+	a := &ActionSchedule{entity: e, event: p.Event}
+	switch {
+	case p.At == nil && p.Event == "":
+		return nil, &FieldError{"params.at", "at or event required"}
+	case p.At != nil && p.Event != "":
+		return nil, &FieldError{"params.event", "at and event are exclusive"}
+	case p.At != nil:
+		if err := checkFinite("params.at", p.At); err != nil {
+			return nil, err
+		}
+		a.at = *p.At
+	default:
+		switch p.Event {
+		case EventPeriapsis, EventApoapsis, EventAscendingNode, EventDescendingNode, EventAtmosphere, EventSOIExit:
+		default:
+			return nil, &FieldError{"params.event", fmt.Sprintf("unknown orbital event %q", p.Event)}
+		}
+	}
+
+	if p.Action == nil {
+		return nil, &FieldError{"params.action", "required"}
+	}
+	if p.Action.Action == "schedule" {
+		return nil, &FieldError{"params.action.action", "cannot schedule a schedule"}
+	}
+	a.env = &ActionEnvelope{
+		Version: actionVersion,
+		Action:  p.Action.Action,
+		Entity:  strconv.FormatUint(uint64(e), 10),
+		Params:  p.Action.Params,
+	}
+	action, err := a.env.decode()
+	if err != nil {
+		if fe, ok := err.(*FieldError); ok {
+			fe.Field = "params.action." + fe.Field
+		}
+		return nil, err
+	}
+	a.action = action
+	return a, nil
+}
+
+func decodeActionCancelTimer(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		Timer string `json:"timer"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	timer, err := decodeId("params.timer", p.Timer)
+	if err != nil {
+		return nil, err
+	}
+	return &ActionCancelTimer{e, timer}, nil
+}
+
+func decodeActionManeuver(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		At       *float64 `json:"at"`
+		Prograde *float64 `json:"prograde"`
+		Normal   *float64 `json:"normal"`
+		Radial   *float64 `json:"radial"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	if err := checkFinite("params.at", p.At); err != nil {
+		return nil, err
+	}
+	dv := new(V3)
+	for _, c := range []struct {
+		field string
+		f     *float64
+		dst   *float64
+	}{{"params.prograde", p.Prograde, &dv.X}, {"params.normal", p.Normal, &dv.Y}, {"params.radial", p.Radial, &dv.Z}} {
+		if c.f == nil {
+			continue
+		}
+		if err := checkFinite(c.field, c.f); err != nil {
+			return nil, err
+		}
+		*c.dst = *c.f
+	}
+	if dv.IsZero() {
+		return nil, &FieldError{"params", "Δv required"}
+	}
+	return &ActionManeuver{entity: e, at: *p.At, dv: dv}, nil
+}
+
+func decodeActionBurn(e Id, params json.RawMessage) (Action, error) {
+	var p burnParams
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	if err := checkV3("params.dv", p.DeltaV); err != nil {
+		return nil, err
+	}
+	if p.DeltaV.IsZero() {
+		return nil, &FieldError{"params.dv", "must not be zero"}
+	}
+	a := &ActionBurn{entity: e, dv: p.DeltaV}
+	if p.Start != nil {
+		if err := checkFinite("params.start", p.Start); err != nil {
+			return nil, err
+		}
+		a.start = *p.Start
+	}
+	return a, nil
+}
+
+func decodeActionHyperdrive(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		Target  string   `json:"target"`
+		Dest    *V3      `json:"dest"`
+		At      *float64 `json:"at"`
+		Heading *V3      `json:"heading"`
+		Speed   *float64 `json:"speed"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+
+	a := &ActionHyperdrive{entity: e}
+	modes := 0
+	for _, set := range []bool{p.Target != "", p.Dest != nil, p.Heading != nil} {
+		if set {
+			modes++
+		}
+	}
+	switch {
+	case modes == 0:
+		return nil, &FieldError{"params.target", "target, dest or heading required"}
+	case modes > 1:
+		return nil, &FieldError{"params", "target, dest and heading are exclusive"}
+	case p.Heading != nil:
+		if err := checkV3("params.heading", p.Heading); err != nil {
+			return nil, err
+		}
+		if p.Heading.IsZero() {
+			return nil, &FieldError{"params.heading", "must not be zero"}
+		}
+		if err := checkFinite("params.speed", p.Speed); err != nil {
+			return nil, err
+		}
+		if *p.Speed <= 0 {
+			return nil, &FieldError{"params.speed", "must be positive"}
+		}
+		if p.At != nil {
+			return nil, &FieldError{"params.at", "not valid with heading"}
+		}
+		a.heading, a.speed = p.Heading, *p.Speed
+		return a, nil
+	case p.Dest != nil:
+		if err := checkV3("params.dest", p.Dest); err != nil {
+			return nil, err
+		}
+		a.dest = p.Dest
+	default:
+		target, err := decodeId("params.target", p.Target)
+		if err != nil {
+			return nil, err
+		}
+		a.target = target
+	}
+	if p.Speed != nil {
+		return nil, &FieldError{"params.speed", "only valid with heading"}
+	}
+	if err := checkFinite("params.at", p.At); err != nil {
+		return nil, err
+	}
+	a.at = *p.At
+	return a, nil
+}
+
+func decodeActionHyperdriveExit(e Id, params json.RawMessage) (Action, error) {
+	var p struct{}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	return &ActionHyperdriveExit{e}, nil
+}
+
+func decodeActionInterdict(e Id, params json.RawMessage) (Action, error) {
+	var p struct {
+		Enable *bool `json:"enable"`
+	}
+	if err := decodeStrict(params, &p, "params."); err != nil {
+		return nil, err
+	}
+	if p.Enable == nil {
+		return nil, &FieldError{"params.enable", "required"}
+	}
+	return &ActionInterdict{e, *p.Enable}, nil
+}
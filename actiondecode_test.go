@@ -0,0 +1,114 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeAction(t *testing.T) {
+	valid := []struct {
+		Name string
+		Msg  string
+	}{
+		{"rotate", `{"version":1,"action":"rotate","entity":"2","params":{"force":{"x":1,"y":0,"z":-1},"duration":2.5}}`},
+		{"thrust", `{"version":1,"action":"thrust","entity":"2","params":{"force":1000,"duration":10}}`},
+		{"gimbal", `{"version":1,"action":"gimbal","entity":"2","params":{"pitch":0.01,"yaw":-0.01}}`},
+		{"attitude", `{"version":1,"action":"attitude","entity":"2","params":{"mode":"prograde"}}`},
+		{"attitude target", `{"version":1,"action":"attitude","entity":"2","params":{"mode":"target","target":"3"}}`},
+		{"desaturate", `{"version":1,"action":"desaturate","entity":"2","params":{"method":"rcs"}}`},
+		{"delegate", `{"version":1,"action":"delegate","entity":"2","params":{"delegate":"0x9a3e41bf84e4e5e4f0b06f2f34a52d3e9ec1d2b1","allow":true}}`},
+		{"maneuver", `{"version":1,"action":"maneuver","entity":"2","params":{"at":600,"prograde":12.5,"radial":-1}}`},
+		{"burn", `{"version":1,"action":"burn","entity":"2","params":{"dv":{"x":1,"y":2,"z":3},"start":100}}`},
+		{"transfer", `{"version":1,"action":"transfer","entity":"2","params":{"to":"0x9a3e41bf84e4e5e4f0b06f2f34a52d3e9ec1d2b1"}}`},
+		{"hyperdrive", `{"version":1,"action":"hyperdrive","entity":"2","params":{"target":"5","at":3600}}`},
+		{"hyperdrive dest", `{"version":1,"action":"hyperdrive","entity":"2","params":{"dest":{"x":1,"y":2,"z":3},"at":3600}}`},
+		{"hyperdrive heading", `{"version":1,"action":"hyperdrive","entity":"2","params":{"heading":{"x":0,"y":0,"z":1},"speed":100}}`},
+		{"dropout", `{"version":1,"action":"dropout","entity":"2","params":{}}`},
+		{"interdict", `{"version":1,"action":"interdict","entity":"2","params":{"enable":true}}`},
+	}
+	for _, tc := range valid {
+		t.Run(tc.Name, func(t *testing.T) {
+			if _, err := DecodeAction([]byte(tc.Msg)); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+
+	// Each case expects an error containing the given string
+	invalid := []struct {
+		Name string
+		Msg  string
+		Err  string
+	}{
+		{"empty", ``, "EOF"},
+		{"not json", `rotate 2`, "invalid character"},
+		{"array", `[1,2]`, "got JSON array"},
+		{"trailing data", `{"version":1,"action":"thrust","entity":"2","params":{"force":1,"duration":1}} {}`, "unexpected data"},
+		{"version", `{"version":2,"action":"thrust","entity":"2","params":{"force":1,"duration":1}}`, "version"},
+		{"missing version", `{"action":"thrust","entity":"2","params":{"force":1,"duration":1}}`, "version"},
+		{"unknown action", `{"version":1,"action":"selfdestruct","entity":"2","params":{}}`, "unknown action"},
+		{"missing action", `{"version":1,"entity":"2","params":{}}`, "unknown action"},
+		{"missing entity", `{"version":1,"action":"thrust","params":{"force":1,"duration":1}}`, "entity: required"},
+		{"entity type", `{"version":1,"action":"thrust","entity":2,"params":{"force":1,"duration":1}}`, "entity"},
+		{"entity value", `{"version":1,"action":"thrust","entity":"-2","params":{"force":1,"duration":1}}`, "invalid entity id"},
+		{"entity zero", `{"version":1,"action":"thrust","entity":"0","params":{"force":1,"duration":1}}`, "invalid entity id"},
+		{"missing params", `{"version":1,"action":"thrust","entity":"2"}`, "params: required"},
+		{"unknown field", `{"version":1,"action":"thrust","entity":"2","params":{"force":1,"duration":1},"x":1}`, "unknown field"},
+		{"unknown param", `{"version":1,"action":"thrust","entity":"2","params":{"force":1,"duration":1,"boost":true}}`, "unknown field"},
+		{"missing duration", `{"version":1,"action":"thrust","entity":"2","params":{"force":1}}`, "params.duration: required"},
+		{"negative duration", `{"version":1,"action":"thrust","entity":"2","params":{"force":1,"duration":-1}}`, "params.duration"},
+		{"zero duration", `{"version":1,"action":"rotate","entity":"2","params":{"force":{"x":1},"duration":0}}`, "params.duration"},
+		{"huge duration", `{"version":1,"action":"rotate","entity":"2","params":{"force":{"x":1},"duration":1e300}}`, "params.duration"},
+		{"overflow", `{"version":1,"action":"thrust","entity":"2","params":{"force":1e400,"duration":1}}`, "force"},
+		{"negative thrust", `{"version":1,"action":"thrust","entity":"2","params":{"force":-5,"duration":1}}`, "params.force"},
+		{"thrust type", `{"version":1,"action":"thrust","entity":"2","params":{"force":"max","duration":1}}`, "params.force: expected"},
+		{"torque type", `{"version":1,"action":"rotate","entity":"2","params":{"force":5,"duration":1}}`, "params.force: expected"},
+		{"missing torque", `{"version":1,"action":"rotate","entity":"2","params":{"duration":1}}`, "params.force: required"},
+		{"attitude mode", `{"version":1,"action":"attitude","entity":"2","params":{"mode":"sideways"}}`, "params.mode"},
+		{"attitude target", `{"version":1,"action":"attitude","entity":"2","params":{"mode":"target"}}`, "params.target: required"},
+		{"attitude stray target", `{"version":1,"action":"attitude","entity":"2","params":{"mode":"normal","target":"3"}}`, "params.target"},
+		{"desaturate method", `{"version":1,"action":"desaturate","entity":"2","params":{"method":"magic"}}`, "params.method"},
+		{"delegate address", `{"version":1,"action":"delegate","entity":"2","params":{"delegate":"0x9a3e","allow":true}}`, "params.delegate: invalid address"},
+		{"delegate allow", `{"version":1,"action":"delegate","entity":"2","params":{"delegate":"0x9a3e41bf84e4e5e4f0b06f2f34a52d3e9ec1d2b1"}}`, "params.allow: required"},
+		{"transfer missing to", `{"version":1,"action":"transfer","entity":"2","params":{}}`, "params.to: required"},
+		{"maneuver missing at", `{"version":1,"action":"maneuver","entity":"2","params":{"prograde":1}}`, "params.at: required"},
+		{"maneuver zero", `{"version":1,"action":"maneuver","entity":"2","params":{"at":600}}`, "Δv required"},
+		{"burn zero", `{"version":1,"action":"burn","entity":"2","params":{"dv":{"x":0,"y":0,"z":0}}}`, "params.dv: must not be zero"},
+		{"hyperdrive missing target", `{"version":1,"action":"hyperdrive","entity":"2","params":{"at":3600}}`, "params.target: target, dest or heading required"},
+		{"hyperdrive exclusive", `{"version":1,"action":"hyperdrive","entity":"2","params":{"target":"5","heading":{"x":1},"speed":1}}`, "exclusive"},
+		{"hyperdrive zero heading", `{"version":1,"action":"hyperdrive","entity":"2","params":{"heading":{"x":0},"speed":1}}`, "params.heading: must not be zero"},
+		{"hyperdrive missing speed", `{"version":1,"action":"hyperdrive","entity":"2","params":{"heading":{"x":1}}}`, "params.speed: required"},
+		{"hyperdrive stray speed", `{"version":1,"action":"hyperdrive","entity":"2","params":{"target":"5","at":3600,"speed":1}}`, "params.speed: only valid with heading"},
+		{"hyperdrive missing at", `{"version":1,"action":"hyperdrive","entity":"2","params":{"target":"5"}}`, "params.at: required"},
+		{"interdict missing enable", `{"version":1,"action":"interdict","entity":"2","params":{}}`, "params.enable: required"},
+		{"gimbal missing yaw", `{"version":1,"action":"gimbal","entity":"2","params":{"pitch":0}}`, "params.yaw: required"},
+	}
+	for _, tc := range invalid {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := DecodeAction([]byte(tc.Msg))
+			if err == nil {
+				t.Fatalf("expected error")
+			}
+			if !strings.Contains(err.Error(), tc.Err) {
+				t.Errorf("got error %q, want it to contain %q", err, tc.Err)
+			}
+		})
+	}
+}
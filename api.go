@@ -44,6 +44,8 @@ type EntitySubData struct {
 
 	Ori *Q
 	Rot *V3
+
+	Timers []*Timer
 }
 
 func (es *EntitySub) Update() {
@@ -59,6 +61,7 @@ func (es *EntitySub) Update() {
 		Vel: S.Vel[e],
 		Ori: S.Ori[e],
 		Rot: S.Rot[e].R, // TODO: include body/world transform?
+		Timers: S.Timers.ForEntity(e),
 	}
 
 	b, err := json.Marshal(data)
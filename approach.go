@@ -0,0 +1,136 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"errors"
+	"math"
+)
+
+// Closest approach prediction between two orbiters around the same
+// primary, e.g. for docking, interception and collision warnings.
+
+// Approach is a local minimum of the distance between two orbiters.
+type Approach struct {
+	Time     float64 `json:"time"` // seconds from the orbits' current positions
+	Distance float64 `json:"distance"`
+
+	// Velocity of the target relative the orbiter
+	RelVel *V3 `json:"relVel"`
+}
+
+// relativeState returns the position and velocity of the target relative
+// the orbiter, t seconds from their current positions.
+func (o *OE) relativeState(target *OE, t float64) (*V3, *V3) {
+	r1, v1 := o.AtTime(t).OrbitalToStateVector()
+	r2, v2 := target.AtTime(t).OrbitalToStateVector()
+	return r2.Sub(r2, r1), v2.Sub(v2, v1)
+}
+
+// Approaches returns the approaches of the orbiter and the target orbiter,
+// around the same primary, during the next span seconds closer than
+// threshold, in time order.  The start and end of the span count as
+// approaches if the distance is increasing and decreasing there.  Changes
+// of the range rate are found by findCrossings, over the shorter of the two
+// periods, and the span is capped at approachMaxPeriods of it.
+func (o *OE) Approaches(target *OE, span, threshold float64) []*Approach {
+	approaches := []*Approach{}
+	for _, a := range o.approaches(target, span) {
+		if a.Distance < threshold {
+			approaches = append(approaches, a)
+		}
+	}
+	return approaches
+}
+
+// ClosestApproach returns the closest approach of the orbiter and the
+// target orbiter, around the same primary, during the next span seconds.
+// See Approaches.
+func (o *OE) ClosestApproach(target *OE, span float64) *Approach {
+	var closest *Approach
+	for _, a := range o.approaches(target, span) {
+		if closest == nil || a.Distance < closest.Distance {
+			closest = a
+		}
+	}
+	return closest
+}
+
+func (o *OE) approaches(target *OE, span float64) []*Approach {
+	// closing returns whether the distance decreases at time t
+	closing := func(t float64) bool {
+		r, v := o.relativeState(target, t)
+		return r.ScalarProduct(v) < 0
+	}
+	approach := func(t float64) *Approach {
+		r, v := o.relativeState(target, t)
+		return &Approach{t, r.Magnitude(), v}
+	}
+
+	period := math.Min(o.Period(), target.Period())
+	span = math.Min(span, approachMaxPeriods*period)
+	crossings := findCrossings(closing, span, period, approachSamplesPerOrbit, approachTimeTolerance)
+
+	// the range rate alternates sign at each crossing; approaches are where
+	// the distance stops decreasing
+	approaches := []*Approach{}
+	wasClosing := closing(0)
+	if !wasClosing {
+		approaches = append(approaches, approach(0))
+	}
+	for _, t := range crossings {
+		if wasClosing {
+			approaches = append(approaches, approach(t))
+		}
+		wasClosing = !wasClosing
+	}
+	if wasClosing {
+		approaches = append(approaches, approach(span))
+	}
+	return approaches
+}
+
+// EntityClosestApproach returns the closest approach of the entity and the
+// target entity during the next span seconds, at most approachMaxPeriods
+// orbital periods.  See Approaches.
+func EntityClosestApproach(e, target Id, span float64) (*Approach, error) {
+	if err := checkApproachEntities(e, target); err != nil {
+		return nil, err
+	}
+	return S.Orb[e].ClosestApproach(S.Orb[target], span), nil
+}
+
+// EntityApproaches returns the approaches of the entity and the target
+// entity during the next span seconds, at most approachMaxPeriods orbital
+// periods, closer than threshold.  See Approaches.
+func EntityApproaches(e, target Id, span, threshold float64) ([]*Approach, error) {
+	if err := checkApproachEntities(e, target); err != nil {
+		return nil, err
+	}
+	return S.Orb[e].Approaches(S.Orb[target], span, threshold), nil
+}
+
+func checkApproachEntities(e, target Id) error {
+	if S.Orb[e] == nil || S.Orb[target] == nil {
+		return errors.New("both entities must be in orbit")
+	}
+	if S.EntFrames[e] != S.EntFrames[target] {
+		return errors.New("entities orbit different primaries")
+	}
+	return nil
+}
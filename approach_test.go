@@ -0,0 +1,86 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func TestApproaches(t *testing.T) {
+	μ, r, T := 398600.0, 7000.0, 1000.0
+	v := math.Sqrt(μ / r)
+	i := 0.5
+
+	// both orbiters pass (r, 0, 0) at T, and (-r, 0, 0) half a period later
+	o := StateVectorToOrbital(&V3{r, 0, 0}, &V3{0, v, 0}, μ).AtTime(-T)
+	target := StateVectorToOrbital(&V3{r, 0, 0}, &V3{0, v * math.Cos(i), v * math.Sin(i)}, μ).AtTime(-T)
+
+	approaches := o.Approaches(target, o.Period(), 1)
+	if len(approaches) != 2 {
+		t.Fatalf("got %v approaches, expected 2", len(approaches))
+	}
+	for j, ex := range []float64{T, T + o.Period()/2} {
+		a := approaches[j]
+		if math.Abs(a.Time-ex) > 1e-2 || a.Distance > 1e-1 {
+			t.Errorf("approach %v at %v distance %v, expected at %v", j, a.Time, a.Distance, ex)
+		}
+		if exV := 2 * v * math.Sin(i/2); math.Abs(a.RelVel.Magnitude()-exV) > 1e-6 {
+			t.Errorf("approach %v relative speed %v, expected %v", j, a.RelVel.Magnitude(), exV)
+		}
+	}
+
+	// approaches not closer than the threshold are left out, but the
+	// closest approach is always returned
+	if approaches := o.Approaches(target, T/2, 1); len(approaches) != 0 {
+		t.Errorf("got %v approaches, expected none", len(approaches))
+	}
+	a := o.ClosestApproach(target, T/2)
+	if a == nil || a.Time != T/2 {
+		t.Errorf("got closest approach %v, expected at end of span %v", a, T/2)
+	}
+
+	// long spans are capped at approachMaxPeriods periods
+	approaches = o.Approaches(target, 1e12, 1)
+	if n := len(approaches); n != 2*approachMaxPeriods {
+		t.Errorf("got %v approaches over a long span, expected %v", n, 2*approachMaxPeriods)
+	}
+	if last := approaches[len(approaches)-1]; last.Time > approachMaxPeriods*o.Period() {
+		t.Errorf("approach at %v past %v periods", last.Time, approachMaxPeriods)
+	}
+}
+
+func TestClosestApproach(t *testing.T) {
+	μ, r1, r2, T := 398600.0, 7000.0, 7100.0, 1500.0
+	v1, v2 := math.Sqrt(μ/r1), math.Sqrt(μ/r2)
+
+	// coplanar circular orbits, aligned at T
+	o := StateVectorToOrbital(&V3{r1, 0, 0}, &V3{0, v1, 0}, μ).AtTime(-T)
+	target := StateVectorToOrbital(&V3{r2, 0, 0}, &V3{0, v2, 0}, μ).AtTime(-T)
+
+	a := o.ClosestApproach(target, 2*T)
+	if a == nil {
+		t.Fatal("no closest approach")
+	}
+	if math.Abs(a.Time-T) > 1e-2 || math.Abs(a.Distance-(r2-r1)) > 1e-6 {
+		t.Errorf("closest approach at %v distance %v, expected at %v distance %v", a.Time, a.Distance, T, r2-r1)
+	}
+	if ex := (&V3{0, v2 - v1, 0}); new(V3).Sub(a.RelVel, ex).Magnitude() > 1e-6 {
+		t.Errorf("relative velocity %v, expected %v", a.RelVel.Fmt(), ex.Fmt())
+	}
+}
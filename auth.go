@@ -0,0 +1,130 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Entity ownership and authorization of actions.
+//
+// Each controllable entity is owned by a user account.  The owner may
+// delegate control of the entity to other accounts, e.g. a fleet
+// commander or an autopilot bot; delegates may execute any action on the
+// entity except changing its ownership or delegates.
+//
+// The engine authorizes every user action before executing it.  Actions
+// originating from the engine itself are always authorized.  Failed
+// authorizations are recorded in the engine's audit log.
+
+// ownerAction is implemented by actions only the entity owner may execute.
+type ownerAction interface {
+	ownerOnly()
+}
+
+// SetOwner sets the owning account of entity e, clearing its delegates.
+func (s *State) SetOwner(e Id, owner common.Address) {
+	s.Owner[e] = owner
+	delete(s.Delegates, e)
+}
+
+// SetDelegate grants or revokes control of entity e to account d.
+func (s *State) SetDelegate(e Id, d common.Address, allow bool) {
+	if !allow {
+		delete(s.Delegates[e], d)
+		if len(s.Delegates[e]) == 0 {
+			delete(s.Delegates, e)
+		}
+		return
+	}
+	if s.Delegates[e] == nil {
+		s.Delegates[e] = make(map[common.Address]bool)
+	}
+	s.Delegates[e][d] = true
+}
+
+// Authorize returns nil if account a may execute action on its entity.
+func (s *State) Authorize(a common.Address, action Action) error {
+	e := action.Entity()
+	owner, ok := s.Owner[e]
+	if !ok {
+		return fmt.Errorf("entity %v has no owner", e)
+	}
+	if a == owner {
+		return nil
+	}
+	if _, ok := action.(ownerAction); ok {
+		return fmt.Errorf("%v is not the owner of entity %v", a.Hex(), e)
+	}
+	if !s.Delegates[e][a] {
+		return fmt.Errorf("%v is not authorized for entity %v", a.Hex(), e)
+	}
+	return nil
+}
+
+// AuditEntry records a failed authorization.
+type AuditEntry struct {
+	Frame  uint64         `json:"frame"`
+	Signer common.Address `json:"signer"`
+	Entity Id             `json:"entity"`
+	Action string         `json:"action"`
+	Reason string         `json:"reason"`
+}
+
+// AuditLog holds the most recent auditLogSize entries.  It is written by
+// the engine loop and may be read concurrently, e.g. by an admin API.
+type AuditLog struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+	next    int
+}
+
+func (l *AuditLog) record(entry AuditEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) < auditLogSize {
+		l.entries = append(l.entries, entry)
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % auditLogSize
+}
+
+// Entries returns the audit log entries, oldest first.
+func (l *AuditLog) Entries() []AuditEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries := make([]AuditEntry, 0, len(l.entries))
+	entries = append(entries, l.entries[l.next:]...)
+	return append(entries, l.entries[:l.next]...)
+}
+
+// actionName returns the name of the action type for audit entries,
+// e.g. "EngineThrust" for *ActionEngineThrust.
+func actionName(a Action) string {
+	t := reflect.TypeOf(a)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return strings.TrimPrefix(t.Name(), "Action")
+}
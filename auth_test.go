@@ -0,0 +1,178 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestAuthorize(t *testing.T) {
+	ResetState()
+	owner := common.HexToAddress("0x01")
+	commander := common.HexToAddress("0x02")
+	stranger := common.HexToAddress("0x03")
+
+	ship := DevNewShip()
+	unowned := DevNewShip()
+	S.SetOwner(ship, owner)
+	S.SetDelegate(ship, commander, true)
+
+	thrust := &ActionEngineThrust{ship, 100000, 1}
+	delegate := &ActionSetDelegate{ship, stranger, true}
+
+	cases := []struct {
+		Name   string
+		Signer common.Address
+		Action Action
+		Ok     bool
+	}{
+		{"owner", owner, thrust, true},
+		{"delegate", commander, thrust, true},
+		{"stranger", stranger, thrust, false},
+		{"unowned", owner, &ActionEngineThrust{unowned, 1000, 1}, false},
+		{"owner delegates", owner, delegate, true},
+		{"delegate delegates", commander, delegate, false},
+		{"delegate transfers", commander, &ActionTransferOwnership{ship, commander}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := S.Authorize(tc.Signer, tc.Action)
+			if (err == nil) != tc.Ok {
+				t.Errorf("got %v, want ok %v", err, tc.Ok)
+			}
+		})
+	}
+
+	// revoking and transferring drop delegated control
+	S.SetDelegate(ship, commander, false)
+	if S.Authorize(commander, thrust) == nil {
+		t.Error("revoked delegate authorized")
+	}
+	S.SetDelegate(ship, commander, true)
+	S.SetOwner(ship, stranger)
+	if S.Authorize(commander, thrust) == nil || S.Authorize(owner, thrust) == nil {
+		t.Error("previous owner or delegate authorized after transfer")
+	}
+}
+
+func TestUnauthorizedActionAudit(t *testing.T) {
+	ship := devOrbitingShip()
+	owner := common.HexToAddress("0x01")
+	stranger := common.HexToAddress("0x03")
+	S.SetOwner(ship, owner)
+
+	ge := &GameEngine{actionChan: make(chan *ActionRequest, 2)}
+	ge.frame = 3
+	results := make(chan *ActionResult, 2)
+	ge.actionChan <- &ActionRequest{Id: 1, Action: &ActionEngineThrust{ship, 100000, 1}, Results: results, Signer: stranger}
+	ge.actionChan <- &ActionRequest{Id: 2, Action: &ActionEngineThrust{ship, 100000, 1}, Results: results, Signer: owner}
+	ge.handleUserActions()
+
+	if r := <-results; r.Id != 1 || r.Status != ActionRejected {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if r := <-results; r.Id != 2 || r.Status != ActionApplied {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	entries := ge.AuditLog()
+	if len(entries) != 1 {
+		t.Fatalf("%d audit entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Frame != 3 || e.Signer != stranger || e.Entity != ship || e.Action != "EngineThrust" {
+		t.Errorf("unexpected audit entry %+v", e)
+	}
+	if m := ge.Metrics(); m.ActionsUnauthorized != 1 || m.ActionsRejected != 1 || m.ActionsApplied != 1 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+}
+
+func TestAuditLogWraps(t *testing.T) {
+	l := new(AuditLog)
+	for i := 0; i < auditLogSize+5; i++ {
+		l.record(AuditEntry{Frame: uint64(i)})
+	}
+	entries := l.Entries()
+	if len(entries) != auditLogSize {
+		t.Fatalf("%d entries, want %d", len(entries), auditLogSize)
+	}
+	if entries[0].Frame != 5 || entries[auditLogSize-1].Frame != auditLogSize+4 {
+		t.Errorf("entries not oldest first: first %d, last %d", entries[0].Frame, entries[auditLogSize-1].Frame)
+	}
+}
+
+// Actions signed by the owner of the dev ship pass the whole pipeline:
+// decoding, signature and nonce checks, authorization and execution.
+func TestSignedActionOnDevShip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stranger, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := NewDevWorld(1, crypto.PubkeyToAddress(key.PublicKey))
+	defer func(ge *GameEngine) { GE = ge }(GE)
+	GE = &GameEngine{actionChan: make(chan *ActionRequest, 10)}
+	results := make(chan *ActionResult, 10)
+
+	send := func(k *ecdsa.PrivateKey, id uint64) *ActionResult {
+		t.Helper()
+		env := &ActionEnvelope{
+			Version: actionVersion,
+			Id:      id,
+			Action:  "thrust",
+			Entity:  fmt.Sprint(e),
+			Params:  json.RawMessage(`{"force":100000,"duration":10}`),
+			Nonce:   id,
+		}
+		if err := env.Sign(k); err != nil {
+			t.Fatal(err)
+		}
+		msg, err := json.Marshal(env)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := HandleAction(msg, results); err != nil {
+			t.Fatal(err)
+		}
+		if r := <-results; r.Status != ActionAccepted {
+			t.Fatalf("unexpected result %+v", r)
+		}
+		GE.handleUserActions()
+		return <-results
+	}
+
+	if r := send(key, 1); r.Id != 1 || r.Status != ActionApplied {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if len(S.ForceGens[e]) != 1 {
+		t.Errorf("thrust not applied")
+	}
+	if r := send(stranger, 2); r.Status != ActionRejected {
+		t.Errorf("unexpected result %+v", r)
+	}
+}
@@ -0,0 +1,184 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+)
+
+// The attitude autopilot is a closed-loop controller that points a ship's
+// forward vector (see Q.ForwardVector) in a direction derived from the
+// ship's current orbit or towards another entity, and holds it there until
+// cancelled.
+//
+// The controller is a PD controller on the quaternion error between the
+// current and the target orientation.  Its output torque is limited per
+// body axis to the ship's CMG torque cap (see ShipClass.CMGTorqueCap) and
+// to the momentum its CMG can still store (see cmg.go).
+//
+// References:
+//
+// [1] Wie, B., Weiss, H. and Arapostathis, A., 1989. Quaternion feedback
+//     regulator for spacecraft eigenaxis rotations.
+
+type AttitudeMode uint8
+
+const (
+	AttitudeOff AttitudeMode = iota
+	AttitudePrograde
+	AttitudeRetrograde
+	AttitudeNormal
+	AttitudeAntinormal
+	AttitudeRadialIn
+	AttitudeRadialOut
+	AttitudeTarget
+
+	// AttitudeDirection points along a fixed direction in the ref frame.
+	// It is used by the engine (e.g. for maneuver burns) and not
+	// selectable by name.
+	AttitudeDirection
+)
+
+var attitudeModes = map[string]AttitudeMode{
+	"off":        AttitudeOff,
+	"prograde":   AttitudePrograde,
+	"retrograde": AttitudeRetrograde,
+	"normal":     AttitudeNormal,
+	"antinormal": AttitudeAntinormal,
+	"radialin":   AttitudeRadialIn,
+	"radialout":  AttitudeRadialOut,
+	"target":     AttitudeTarget,
+}
+
+// AttitudeForceGen is a force generator generating the torque needed to
+// reach and hold the attitude of its mode.  It never expires by itself;
+// call Cancel to release the ship.
+type AttitudeForceGen struct {
+	Mode AttitudeMode
+
+	// Target entity, used only by AttitudeTarget
+	Target Id
+
+	// Direction in the ref frame, used only by AttitudeDirection
+	Dir *V3
+
+	cancelled bool
+}
+
+func (a *AttitudeForceGen) Cancel() {
+	a.cancelled = true
+}
+
+func (a *AttitudeForceGen) UpdateForce(e Id, elapsed float64) (*V3, *V3) {
+	if a.cancelled {
+		return nil, nil
+	}
+
+	forward, up := attitudeDirection(e, a)
+	if forward == nil {
+		return nil, nil
+	}
+
+	q := S.Ori[e]
+	target := new(Q).SetLookRotation(forward, up)
+
+	// rotation from the current to the target orientation, in world space
+	qe := new(Q).Set(target).Mul(new(Q).Conjugate(q))
+	if qe.R < 0 {
+		// q and -q are the same orientation; take the shorter way around
+		qe.R, qe.I, qe.J, qe.K = -qe.R, -qe.I, -qe.J, -qe.K
+	}
+	axis, angle := qe.AxisAngle()
+
+	// PD control law: desired angular acceleration in world space
+	kp := attitudeNaturalFreq * attitudeNaturalFreq
+	kd := 2 * attitudeDamping * attitudeNaturalFreq
+	acc := new(V3).MulScalar(axis, kp*angle)
+	acc.AddScaledVector(S.Rot[e].R, -kd)
+
+	// torque in body space from the body space inertia tensor
+	inertia := new(M3)
+	*inertia = *S.Rot[e].IITB
+	inertia.Inverse()
+	torque := inertia.Transform(new(Q).Conjugate(q).Rotate(acc))
+
+	max := S.ShipClass[e].CMGTorqueCap()
+	torque.X = math.Max(-max.X, math.Min(max.X, torque.X))
+	torque.Y = math.Max(-max.Y, math.Min(max.Y, torque.Y))
+	torque.Z = math.Max(-max.Z, math.Min(max.Z, torque.Z))
+
+	return nil, q.Rotate(cmgTorque(e, torque, elapsed))
+}
+
+func (a *AttitudeForceGen) IsExpired() bool {
+	return a.cancelled
+}
+
+// attitudeDirection returns the forward and up direction of the attitude
+// mode of a for entity e, or nil if the direction is undefined (e.g.
+// prograde for an entity without velocity).
+func attitudeDirection(e Id, a *AttitudeForceGen) (*V3, *V3) {
+	pos, vel := S.EntityState(e, 0)
+	normal := new(V3).VectorProduct(pos, vel)
+
+	var forward, up *V3
+	switch a.Mode {
+	case AttitudePrograde:
+		forward, up = vel, pos
+	case AttitudeRetrograde:
+		forward, up = new(V3).MulScalar(vel, -1), pos
+	case AttitudeNormal:
+		forward, up = normal, pos
+	case AttitudeAntinormal:
+		forward, up = new(V3).MulScalar(normal, -1), pos
+	case AttitudeRadialIn:
+		forward, up = new(V3).MulScalar(pos, -1), normal
+	case AttitudeRadialOut:
+		forward, up = pos, normal
+	case AttitudeTarget:
+		if S.EntFrames[a.Target] == nil {
+			return nil, nil
+		}
+		tpos := entityPosIn(a.Target, S.EntFrames[e], 0)
+		forward, up = tpos.Sub(tpos, pos), normal
+	case AttitudeDirection:
+		if a.Dir == nil {
+			return nil, nil
+		}
+		forward, up = new(V3).Set(a.Dir), normal
+	default:
+		return nil, nil
+	}
+
+	if forward.IsZero() {
+		return nil, nil
+	}
+	if up.IsZero() {
+		up = KHat
+	}
+	return forward, up
+}
+
+// cancelAttitude cancels all attitude force generators of the entity.
+func cancelAttitude(e Id) {
+	for _, fg := range S.ForceGens[e] {
+		if a, ok := fg.(*AttitudeForceGen); ok {
+			a.Cancel()
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func devOrbitingShip() Id {
+	ResetState()
+	planet := &Planet{
+		Entity: S.NewEntity(),
+		Mass:   0.107 * earthMass,
+		Radius: 0.533 * earthRadius,
+	}
+	rf := &RefFrame{Parent: rootRF}
+	S.AddPlanet(planet, rf)
+
+	e := DevNewShip()
+	S.EntFrames[e] = rf
+	S.Orb[e] = planet.DefaultOrbit()
+	return e
+}
+
+func TestAttitudeHoldPrograde(t *testing.T) {
+	e := devOrbitingShip()
+
+	if err := (&ActionAttitude{e, AttitudePrograde, 0}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 120; i++ {
+		updateClassicalMechanics(float64(i), 1.0, S.EntFrames[e], e)
+	}
+
+	_, vel := S.EntityState(e, 0)
+	vel.Normalise()
+	fv := S.Ori[e].ForwardVector()
+	if angle := RadToDeg(math.Acos(fv.ScalarProduct(vel))); angle > 1.0 {
+		t.Errorf("forward vector %.3f degrees off prograde", angle)
+	}
+
+	// cancelling releases the ship
+	if err := (&ActionAttitude{e, AttitudeOff, 0}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	updateClassicalMechanics(120, 1.0, S.EntFrames[e], e)
+	if len(S.ForceGens[e]) != 0 {
+		t.Errorf("force gens left after cancel: %d", len(S.ForceGens[e]))
+	}
+}
+
+// attitudeError returns the angle in degrees between the forward vector of
+// the entity and the direction dir returns.
+func attitudeError(e Id, dir func(pos, vel *V3) *V3) float64 {
+	pos, vel := S.EntityState(e, 0)
+	ex := new(V3).Set(dir(pos, vel))
+	ex.Normalise()
+	cos := S.Ori[e].ForwardVector().ScalarProduct(ex)
+	return RadToDeg(math.Acos(math.Max(-1, math.Min(1, cos))))
+}
+
+func TestAttitudeModes(t *testing.T) {
+	normal := func(pos, vel *V3) *V3 { return new(V3).VectorProduct(pos, vel) }
+	cases := []struct {
+		Name string
+		Mode AttitudeMode
+		Dir  func(pos, vel *V3) *V3
+	}{
+		{"prograde", AttitudePrograde, func(pos, vel *V3) *V3 { return vel }},
+		{"retrograde", AttitudeRetrograde, func(pos, vel *V3) *V3 { return new(V3).MulScalar(vel, -1) }},
+		{"normal", AttitudeNormal, normal},
+		{"antinormal", AttitudeAntinormal, func(pos, vel *V3) *V3 {
+			n := normal(pos, vel)
+			return n.MulScalar(n, -1)
+		}},
+		{"radial in", AttitudeRadialIn, func(pos, vel *V3) *V3 { return new(V3).MulScalar(pos, -1) }},
+		{"radial out", AttitudeRadialOut, func(pos, vel *V3) *V3 { return pos }},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			e := devOrbitingShip()
+			checkAttitudeSettles(t, e, &ActionAttitude{e, tc.Mode, 0}, tc.Dir)
+		})
+	}
+}
+
+func TestAttitudeTarget(t *testing.T) {
+	e := devOrbitingShip()
+	target := S.NewEntity()
+	S.EntFrames[target] = S.EntFrames[e]
+	S.Pos[target] = &V3{0, 0, 1e7}
+
+	checkAttitudeSettles(t, e, &ActionAttitude{e, AttitudeTarget, target}, func(pos, vel *V3) *V3 {
+		return new(V3).Sub(S.Pos[target], pos)
+	})
+}
+
+// checkAttitudeSettles executes the attitude action and checks the ship
+// turns to dir within 1 degree and, once there, stays within it: the PD
+// controller does not overshoot past the tolerance.
+func checkAttitudeSettles(t *testing.T, e Id, a *ActionAttitude, dir func(pos, vel *V3) *V3) {
+	t.Helper()
+	if err := a.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	settled := -1
+	for i := 0; i < 300; i++ {
+		updateClassicalMechanics(float64(i), 1.0, S.EntFrames[e], e)
+		angle := attitudeError(e, dir)
+		switch {
+		case angle <= 1.0 && settled < 0:
+			settled = i
+		case angle > 1.0 && settled >= 0:
+			t.Fatalf("overshot to %.3f degrees at %v s, after settling at %v s", angle, i, settled)
+		}
+	}
+	if settled < 0 {
+		t.Errorf("forward vector %.3f degrees off", attitudeError(e, dir))
+	}
+}
@@ -19,8 +19,10 @@
 package main
 
 import (
+	"fmt"
 	"os"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/urfave/cli"
 
@@ -48,18 +50,96 @@ func main() {
 			Value: 0,
 			Usage: "sets `UINT64` as deterministic seed",
 		},
+		&cli.StringFlag{
+			Name:  "owner",
+			Usage: "gives the dev ship to account `ADDRESS`, which signs its actions",
+		},
 	}
 
 	app.Action = func(c *cli.Context) error {
 		log.Info("==== starburst")
+		owner := common.Address{}
+		if addr := c.String("owner"); addr != "" {
+			if !common.IsHexAddress(addr) {
+				return fmt.Errorf("invalid owner address %q", addr)
+			}
+			owner = common.HexToAddress(addr)
+		}
 		// TODO: pass rand seed
-		tesseract.DevWorld(c.Uint64("testseed"))
+		tesseract.DevWorld(c.Uint64("testseed"), owner)
 		tesseract.StartWebSocket()
 		return nil
 	}
 
+	app.Commands = []cli.Command{
+		{
+			Name:  "oem",
+			Usage: "export the trajectory of an entity of the dev world as a CCSDS OEM",
+			Flags: []cli.Flag{
+				&cli.Uint64Flag{
+					Name:  "entity",
+					Usage: "exports entity `ID` (default: the dev ship)",
+				},
+				&cli.Float64Flag{
+					Name:  "start",
+					Value: 0,
+					Usage: "starts the trajectory at world time `SECONDS`",
+				},
+				&cli.Float64Flag{
+					Name:  "stop",
+					Value: 24 * 3600,
+					Usage: "stops the trajectory at world time `SECONDS`",
+				},
+				&cli.Float64Flag{
+					Name:  "step",
+					Value: 60,
+					Usage: "samples the trajectory every `SECONDS`",
+				},
+				&cli.UintFlag{
+					Name:  "frame",
+					Value: 0,
+					Usage: "exports relative the ref frame `N` levels above the entity's frame",
+				},
+				&cli.StringFlag{
+					Name:  "out",
+					Usage: "writes the OEM to `FILE` (default: stdout)",
+				},
+			},
+			Action: exportOEM,
+		},
+	}
+
 	err := app.Run(os.Args)
 	if err != nil {
 		log.Error("app.Run:", "err", err)
 	}
 }
+
+func exportOEM(c *cli.Context) error {
+	e := tesseract.NewDevWorld(c.GlobalUint64("testseed"), common.Address{})
+	if c.IsSet("entity") {
+		e = tesseract.Id(c.Uint64("entity"))
+	}
+
+	rf := tesseract.S.EntFrames[e]
+	if rf == nil {
+		return fmt.Errorf("unknown entity %v", e)
+	}
+	for i := uint(0); i < c.Uint("frame"); i++ {
+		if rf.IsRoot() {
+			return fmt.Errorf("entity %v has no frame %v levels up", e, c.Uint("frame"))
+		}
+		rf = rf.Parent
+	}
+
+	w := os.Stdout
+	if path := c.String("out"); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return tesseract.WriteOEM(w, e, rf, c.Float64("start"), c.Float64("stop"), c.Float64("step"))
+}
@@ -0,0 +1,179 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"fmt"
+	"math"
+)
+
+// Control Moment Gyroscopes (CMGs) rotate a ship by exchanging angular
+// momentum between the ship and a set of spinning gyroscopes: any torque
+// delivered to the ship is stored, with opposite sign, in the gyroscopes.
+//
+// The gyroscopes can only store a limited amount of angular momentum per
+// body axis (see ShipClass.CMGMomentumCap).  Once saturated, a CMG cannot
+// deliver more torque in the same direction until the stored momentum is
+// dumped by an external torque (desaturation), either by reaction control
+// system (RCS) thrusters or by magnetorquers pushing against the magnetic
+// field of a nearby planet.
+//
+// As the momentum capacity is fixed, the maximum rotation rate of a ship
+// is inversely proportional to its moment of inertia: heavy cargo and
+// modules make ships turn slower.
+
+// CMG holds the angular momentum, in body space, stored in the control
+// moment gyroscopes of a ship.
+type CMG struct {
+	H *V3
+}
+
+type DesatMethod uint8
+
+const (
+	DesatRCS DesatMethod = iota
+	DesatMagnetic
+)
+
+var desatMethods = map[string]DesatMethod{
+	"rcs":      DesatRCS,
+	"magnetic": DesatMagnetic,
+}
+
+// CanDeliver returns an error if the CMG would saturate when delivering
+// the body space torque for duration seconds.
+func (c *CMG) CanDeliver(torque *V3, duration float64, max V3) error {
+	h := new(V3).Set(c.H).AddScaledVector(torque, -duration)
+	if math.Abs(h.X) > max.X || math.Abs(h.Y) > max.Y || math.Abs(h.Z) > max.Z {
+		return fmt.Errorf("CMG momentum %v %v %v exceeds cap %v %v %v", h.X, h.Y, h.Z, max.X, max.Y, max.Z)
+	}
+	return nil
+}
+
+// deliver returns the part of the requested body space torque the CMG can
+// deliver for elapsed seconds and stores the corresponding momentum.
+func (c *CMG) deliver(torque *V3, elapsed float64, max V3) *V3 {
+	axis := func(h *float64, t, max float64) float64 {
+		h2 := *h - t*elapsed
+		if h2 > max {
+			h2 = max
+		}
+		if h2 < -max {
+			h2 = -max
+		}
+		t = (*h - h2) / elapsed
+		*h = h2
+		return t
+	}
+
+	if elapsed <= 0 {
+		return new(V3)
+	}
+	return &V3{
+		axis(&c.H.X, torque.X, max.X),
+		axis(&c.H.Y, torque.Y, max.Y),
+		axis(&c.H.Z, torque.Z, max.Z),
+	}
+}
+
+// cmgTorque returns the body space torque the CMGs of entity e deliver
+// for elapsed seconds, which is at most the requested torque.
+// Entities without CMGs deliver the requested torque.
+func cmgTorque(e Id, torque *V3, elapsed float64) *V3 {
+	if S.CMG[e] == nil {
+		return torque
+	}
+	return S.CMG[e].deliver(torque, elapsed, S.ShipClass[e].CMGMomentumCap())
+}
+
+// DesatForceGen dumps the momentum stored in a ship's CMGs.
+//
+// The external desaturation torque and the CMG torque cancel out, so the
+// ship's rotation is unaffected and no torque is returned.
+// The force generator expires once the CMGs hold no momentum.
+//
+// TODO: RCS desaturation should consume propellant.
+type DesatForceGen struct {
+	Method DesatMethod
+	done   bool
+}
+
+func (d *DesatForceGen) UpdateForce(e Id, elapsed float64) (*V3, *V3) {
+	c := S.CMG[e]
+	if c == nil {
+		d.done = true
+		return nil, nil
+	}
+
+	var max V3
+	switch d.Method {
+	case DesatRCS:
+		max = S.ShipClass[e].RCSTorqueCap()
+	case DesatMagnetic:
+		b := magneticField(e)
+		m := S.ShipClass[e].MagnetorquerDipoleCap()
+		max = V3{m * b, m * b, m * b}
+	}
+
+	dump := func(h *float64, max float64) {
+		step := max * elapsed
+		if math.Abs(*h) <= step {
+			*h = 0
+		} else {
+			*h -= math.Copysign(step, *h)
+		}
+	}
+	dump(&c.H.X, max.X)
+	dump(&c.H.Y, max.Y)
+	dump(&c.H.Z, max.Z)
+
+	d.done = c.H.IsZero()
+	return nil, nil
+}
+
+func (d *DesatForceGen) IsExpired() bool {
+	return d.done
+}
+
+// magneticField returns the magnetic field strength in tesla (T) at the
+// position of entity e, from the planet of its ref frame (if any).
+// Planetary fields are modelled as dipoles: the field strength falls off
+// with the cube of the distance from the planet's center.
+func magneticField(e Id) float64 {
+	p := S.PlanetInFrame(S.EntFrames[e])
+	if p == nil || p.MagField == 0 {
+		return 0
+	}
+	pos, _ := S.EntityState(e, 0)
+	x := p.Radius / math.Max(p.Radius, pos.Magnitude())
+	return p.MagField * x * x * x
+}
+
+// SetShipMass sets the mass of a ship, e.g. after loading cargo, and
+// updates its inertia tensor accordingly.
+func SetShipMass(e Id, m float64) {
+	*S.Mass[e] = m
+	S.Rot[e].IITB = shipInertiaTensor(m)
+}
+
+// TODO: derive ship shape/size from ship class
+func shipInertiaTensor(m float64) *M3 {
+	it := InertiaTensorCuboid(m, 10, 10, 10)
+	it.Inverse()
+	return it
+}
@@ -0,0 +1,82 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"testing"
+)
+
+func TestCMGSaturation(t *testing.T) {
+	e := devOrbitingShip()
+	hCap := S.ShipClass[e].CMGMomentumCap()
+	tCap := S.ShipClass[e].CMGTorqueCap()
+
+	if err := (&ActionRotate{404, &V3{1, 0, 0}, 1}).Execute(); err == nil {
+		t.Errorf("expected error for entity without ship class")
+	}
+
+	// full torque for longer than the momentum capacity allows
+	long := 2 * hCap.X / tCap.X
+	if err := (&ActionRotate{e, &V3{tCap.X, 0, 0}, long}).Execute(); err == nil {
+		t.Errorf("expected saturation error")
+	}
+
+	half := 0.5 * hCap.X / tCap.X
+	if err := (&ActionRotate{e, &V3{tCap.X, 0, 0}, half}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the CMG clamps delivered torque once saturated
+	c := S.CMG[e]
+	c.H.X = -hCap.X + 1
+	got := c.deliver(&V3{tCap.X, 0, 0}, 1, hCap)
+	if got.X != 1 || c.H.X != -hCap.X {
+		t.Errorf("got torque %v momentum %v, want 1 and %v", got.X, c.H.X, -hCap.X)
+	}
+
+	// torque in the opposite direction is unaffected
+	got = c.deliver(&V3{-tCap.X, 0, 0}, 1, hCap)
+	if got.X != -tCap.X {
+		t.Errorf("got torque %v want %v", got.X, -tCap.X)
+	}
+}
+
+func TestCMGDesaturation(t *testing.T) {
+	e := devOrbitingShip()
+	c := S.CMG[e]
+	c.H = &V3{1e6, -3e6, 0}
+
+	if err := (&ActionDesaturate{e, DesatMagnetic}).Execute(); err == nil {
+		t.Errorf("expected error desaturating without a magnetic field")
+	}
+
+	if err := (&ActionDesaturate{e, DesatRCS}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	rate := S.ShipClass[e].RCSTorqueCap().Y
+	ticks := int(3e6/rate) + 1
+	for i := 0; i < ticks; i++ {
+		updateClassicalMechanics(float64(i), 1.0, S.EntFrames[e], e)
+	}
+	if !c.H.IsZero() {
+		t.Errorf("CMG momentum left after desaturation: %v", c.H)
+	}
+	if len(S.ForceGens[e]) != 0 {
+		t.Errorf("desaturation force gen not expired")
+	}
+}
@@ -17,7 +17,154 @@
 */
 package tesseract
 
-//naclbox "github.com/kevinburke/nacl/box"
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	//naclbox "github.com/kevinburke/nacl/box"
+)
+
+// Action signatures
+//
+// Users sign actions with the secp256k1 key of their account, as in
+// Ethereum, and are identified by the address derived from that key.
+// The signature is over the keccak256 hash of the canonical encoding of
+// the action envelope (see SigningHash).  Each action carries a nonce
+// which must be larger than the last nonce used by the signer; this
+// rejects replays of captured actions as well as stale ones.
+
+// actionSigDomain separates action signatures from signatures the same key
+// may make for other purposes, e.g. Ethereum transactions.
+const actionSigDomain = "tesseract action signature\n"
+
+// SigningHash returns the hash signed by the signer of the envelope.
+//
+// The canonical encoding is the domain string followed by the version,
+// id, signer address, nonce, action name, entity and params of the
+// envelope.  Integers are big-endian and strings are prefixed with their
+// uint32 length.  Params are re-encoded as compact JSON with sorted object
+// keys, so the hash does not depend on whitespace or key order.
+func (env *ActionEnvelope) SigningHash() ([]byte, error) {
+	if !common.IsHexAddress(env.Signer) {
+		return nil, &FieldError{"signer", fmt.Sprintf("invalid address %q", env.Signer)}
+	}
+	params, err := canonicalJSON(env.Params)
+	if err != nil {
+		return nil, &FieldError{"params", err.Error()}
+	}
+
+	b := new(bytes.Buffer)
+	b.WriteString(actionSigDomain)
+	binary.Write(b, binary.BigEndian, env.Version)
+	binary.Write(b, binary.BigEndian, env.Id)
+	b.Write(common.HexToAddress(env.Signer).Bytes())
+	binary.Write(b, binary.BigEndian, env.Nonce)
+	for _, str := range []string{env.Action, env.Entity, string(params)} {
+		binary.Write(b, binary.BigEndian, uint32(len(str)))
+		b.WriteString(str)
+	}
+	return crypto.Keccak256(b.Bytes()), nil
+}
+
+// Sign sets the signer of the envelope to the address of key and signs it.
+func (env *ActionEnvelope) Sign(key *ecdsa.PrivateKey) error {
+	env.Signer = crypto.PubkeyToAddress(key.PublicKey).Hex()
+	hash, err := env.SigningHash()
+	if err != nil {
+		return err
+	}
+	sig, err := crypto.Sign(hash, key)
+	if err != nil {
+		return err
+	}
+	env.Signature = hexutil.Encode(sig)
+	return nil
+}
+
+// VerifySignature verifies the envelope signature and returns the signer.
+func (env *ActionEnvelope) VerifySignature() (common.Address, error) {
+	hash, err := env.SigningHash()
+	if err != nil {
+		return common.Address{}, err
+	}
+	if env.Signature == "" {
+		return common.Address{}, &FieldError{"signature", "required"}
+	}
+	sig, err := hexutil.Decode(env.Signature)
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return common.Address{}, &FieldError{"signature", "malformed"}
+	}
+
+	// reject malleable (high s) signatures
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:64])
+	if !crypto.ValidateSignatureValues(sig[64], r, s, true) {
+		return common.Address{}, &FieldError{"signature", "invalid"}
+	}
+
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, &FieldError{"signature", "invalid"}
+	}
+	signer := common.HexToAddress(env.Signer)
+	if crypto.PubkeyToAddress(*pub) != signer {
+		return common.Address{}, &FieldError{"signature", "not signed by signer"}
+	}
+	return signer, nil
+}
+
+// canonicalJSON re-encodes a JSON value compactly with sorted object keys.
+// Numbers are kept as their literal text.
+func canonicalJSON(raw json.RawMessage) ([]byte, error) {
+	if len(raw) == 0 {
+		return []byte{}, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// NonceTracker tracks the last action nonce used by each signer.
+// It is safe for concurrent use, as actions are verified by the
+// connection goroutines before being queued for the engine.
+type NonceTracker struct {
+	mu   sync.Mutex
+	last map[common.Address]uint64
+}
+
+func NewNonceTracker() *NonceTracker {
+	return &NonceTracker{last: make(map[common.Address]uint64)}
+}
+
+// Use records nonce as used by signer.  It fails if nonce is not larger
+// than the last nonce used by signer; nonces start at 1.
+func (nt *NonceTracker) Use(signer common.Address, nonce uint64) error {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+	if last := nt.last[signer]; nonce <= last {
+		return &FieldError{"nonce", fmt.Sprintf("stale nonce %d, last used %d", nonce, last)}
+	}
+	nt.last[signer] = nonce
+	return nil
+}
+
+// Last returns the last nonce used by signer, or 0 if none.
+func (nt *NonceTracker) Last(signer common.Address) uint64 {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+	return nt.last[signer]
+}
 
 /* TODO: this is for testing
 
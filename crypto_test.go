@@ -0,0 +1,174 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func signedTestEnvelope(t *testing.T, nonce uint64) (*ActionEnvelope, []byte) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := &ActionEnvelope{
+		Version: actionVersion,
+		Id:      nonce,
+		Action:  "thrust",
+		Entity:  "2",
+		Params:  json.RawMessage(`{"force":1000,"duration":10}`),
+		Nonce:   nonce,
+	}
+	if err := env.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env, msg
+}
+
+func TestActionSignature(t *testing.T) {
+	env, msg := signedTestEnvelope(t, 1)
+	req, err := DecodeActionRequest(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Signer.Hex() != env.Signer || req.Nonce != 1 {
+		t.Errorf("unexpected signer %v nonce %v", req.Signer.Hex(), req.Nonce)
+	}
+
+	// whitespace and key order of params are not covered by the signature
+	env2 := *env
+	env2.Params = json.RawMessage(`{ "duration": 10,  "force": 1000 }`)
+	if _, err := env2.VerifySignature(); err != nil {
+		t.Errorf("reordered params: %v", err)
+	}
+
+	_, other := signedTestEnvelope(t, 1)
+	otherEnv := new(ActionEnvelope)
+	json.Unmarshal(other, otherEnv)
+
+	tampered := []struct {
+		Name   string
+		Tamper func(e *ActionEnvelope)
+		Err    string
+	}{
+		{"params", func(e *ActionEnvelope) { e.Params = json.RawMessage(`{"force":1001,"duration":10}`) }, "not signed by signer"},
+		{"entity", func(e *ActionEnvelope) { e.Entity = "3" }, "not signed by signer"},
+		{"nonce", func(e *ActionEnvelope) { e.Nonce = 2 }, "not signed by signer"},
+		{"id", func(e *ActionEnvelope) { e.Id = 9 }, "not signed by signer"},
+		{"signer", func(e *ActionEnvelope) { e.Signer = otherEnv.Signer }, "not signed by signer"},
+		{"foreign signature", func(e *ActionEnvelope) { e.Signature = otherEnv.Signature }, "not signed by signer"},
+		{"missing signer", func(e *ActionEnvelope) { e.Signer = "" }, "signer"},
+		{"missing signature", func(e *ActionEnvelope) { e.Signature = "" }, "signature: required"},
+		{"short signature", func(e *ActionEnvelope) { e.Signature = e.Signature[:100] }, "signature: malformed"},
+		{"garbage signature", func(e *ActionEnvelope) { e.Signature = "0xzz" }, "signature: malformed"},
+	}
+	for _, tc := range tampered {
+		t.Run(tc.Name, func(t *testing.T) {
+			e := *env
+			tc.Tamper(&e)
+			_, err := e.VerifySignature()
+			if err == nil || !strings.Contains(err.Error(), tc.Err) {
+				t.Errorf("got error %v, want it to contain %q", err, tc.Err)
+			}
+		})
+	}
+}
+
+func TestActionReplay(t *testing.T) {
+	ResetState()
+	defer func(ge *GameEngine) { GE = ge }(GE)
+	GE = &GameEngine{actionChan: make(chan *ActionRequest, 10)}
+	results := make(chan *ActionResult, 10)
+
+	env, msg := signedTestEnvelope(t, 5)
+	if err := HandleAction(msg, results); err != nil {
+		t.Fatal(err)
+	}
+	if err := HandleAction(msg, results); err == nil || !strings.Contains(err.Error(), "stale nonce") {
+		t.Errorf("replay not rejected: %v", err)
+	}
+	if len(GE.actionChan) != 1 {
+		t.Errorf("%d actions queued, want 1", len(GE.actionChan))
+	}
+
+	r := <-results
+	if r.Id != 5 || r.Status != ActionAccepted {
+		t.Errorf("unexpected result %+v", r)
+	}
+	r = <-results
+	if r.Id != 5 || r.Status != ActionRejected || !strings.Contains(r.Reason, "stale nonce") {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if m := GE.Metrics(); m.ActionsRejected != 1 {
+		t.Errorf("%d actions rejected, want 1", m.ActionsRejected)
+	}
+	if last := S.Nonces.Last(common.HexToAddress(env.Signer)); last != 5 {
+		t.Errorf("last nonce %d, want 5", last)
+	}
+}
+
+func TestNonceTrackerConcurrent(t *testing.T) {
+	nt := NewNonceTracker()
+	_, msg := signedTestEnvelope(t, 1)
+	env := new(ActionEnvelope)
+	json.Unmarshal(msg, env)
+	signer, err := env.VerifySignature()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every nonce is used by exactly one of the competing goroutines
+	const n = 100
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	used := 0
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for nonce := uint64(1); nonce <= n; nonce++ {
+				if nt.Use(signer, nonce) == nil {
+					mu.Lock()
+					used++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if used > n {
+		t.Errorf("%d nonces used, want at most %d", used, n)
+	}
+	if nt.Last(signer) != n {
+		t.Errorf("last nonce %d, want %d", nt.Last(signer), n)
+	}
+	if err := nt.Use(signer, 0); err == nil {
+		t.Error("nonce 0 accepted")
+	}
+}
@@ -46,7 +46,7 @@ func DevScene1() {
 	//S.AddForceGen(e0, &ThrustForceGen{&V3{m0 * g0 * 0.01, 0, 0}})
 	//S.AddForceGen(e0, grid, &DragForceGen{10.0, 40.0})
 
-	actionChan := make(chan Action, 10)
+	actionChan := make(chan *ActionRequest, 10)
 	GE = &GameEngine{
 		systems:    []System{&Physics{}},
 		actionChan: actionChan,
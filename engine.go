@@ -18,6 +18,8 @@
 package tesseract
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -44,8 +46,12 @@ import (
 
 type GameEngine struct {
 	systems    []System
-	actionChan chan Action
+	actionChan chan *ActionRequest
 	subChan    chan *EntitySub
+
+	frame   uint64 // current frame (loop iteration) number
+	metrics EngineMetrics
+	audit   AuditLog
 }
 
 var GE *GameEngine
@@ -58,11 +64,10 @@ func (ge *GameEngine) Loop() error {
 	start = time.Now()
 	last = start
 
-	debug := 0
 	for err == nil {
-		debug++
+		ge.frame++
+		ge.metrics.frame()
 		t0 = time.Now()
-		worldTime := t0.Sub(start)
 		elapsed = t0.Sub(last)
 
 		if elapsed < loopTarget {
@@ -73,19 +78,18 @@ func (ge *GameEngine) Loop() error {
 		} else {
 			last = t0
 		}
+		S.WorldTime += elapsed.Seconds()
+		ge.coastOrbits(elapsed.Seconds())
 
-		err = ge.handleUserActions()
-		if err != nil {
-			break
-		}
-
-		// TODO: ge.handleTimerActions()
+		ge.handleUserActions()
+		ge.handleTimerActions()
 
-		log.Debug("engine.Loop", "c", debug, "run", time.Now().Sub(start))
-		err = ge.update(worldTime.Seconds(), elapsed.Seconds())
+		log.Debug("engine.Loop", "frame", ge.frame, "run", time.Now().Sub(start))
+		err = ge.update(S.WorldTime, elapsed.Seconds())
 		if err != nil {
 			break
 		}
+		ge.postOrbitalEvents(elapsed.Seconds())
 
 		for len(S.EntitySubsCloseChan) > 0 {
 			es := <-S.EntitySubsCloseChan
@@ -124,44 +128,129 @@ func (ge *GameEngine) update(worldTime, elapsed float64) error {
 			}
 		}
 
-		for _, sys := range ge.systems {
-			for e, _ := range S.HotEnts[rf] {
-				if !sys.IsHotPostUpdate(e) {
-					S.SetIdle(e, rf, worldTime)
-				}
-			}
-			if len(S.HotEnts[rf]) == 0 {
-				delete(S.HotEnts, rf)
+		for e, _ := range S.HotEnts[rf] {
+			if !ge.isHotPostUpdate(e) {
+				S.SetIdle(e, rf, worldTime)
 			}
 		}
+		if len(S.HotEnts[rf]) == 0 {
+			delete(S.HotEnts, rf)
+		}
 	}
 	return nil
 }
 
-func (e *GameEngine) handleUserActions() error {
-	var actions []Action
-	select {
-	default:
-		return nil
-	case action := <-e.actionChan:
-		actions = make([]Action, 0)
-		actions = append(actions, action)
-		for i := 1; i < maxActionsPerLoop; i++ {
-			select {
-			default:
-				for _, a := range actions {
-					err := a.Execute()
-					if err != nil {
-						return err
-					}
-				}
-				return nil
-			case a := <-e.actionChan:
-				actions = append(actions, a)
-			}
+// isHotPostUpdate returns whether any system keeps the entity hot.
+func (ge *GameEngine) isHotPostUpdate(e Id) bool {
+	for _, sys := range ge.systems {
+		if sys.IsHotPostUpdate(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// Metrics returns a snapshot of the engine metrics.
+func (ge *GameEngine) Metrics() EngineMetrics {
+	return ge.metrics.Snapshot()
+}
+
+// AuditLog returns the failed action authorizations.
+func (ge *GameEngine) AuditLog() []AuditEntry {
+	return ge.audit.Entries()
+}
+
+// handleUserActions executes up to maxActionsPerLoop queued actions.
+// Actions are isolated from each other and from the engine: a failing
+// action is rejected and reported to its origin, and the remaining
+// actions still execute.
+func (ge *GameEngine) handleUserActions() {
+	for i := 0; i < maxActionsPerLoop; i++ {
+		select {
+		default:
+			return
+		case req := <-ge.actionChan:
+			ge.executeAction(req)
 		}
 	}
+}
 
-	//return errors.New("wtf")
-	return nil
+func (ge *GameEngine) executeAction(req *ActionRequest) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("action panic", "id", req.Id, "panic", r)
+			ge.metrics.actionRejected()
+			req.report(ActionRejected, fmt.Sprintf("internal error: %v", r), 0)
+		}
+	}()
+
+	if !req.engine {
+		if err := S.Authorize(req.Signer, req.Action); err != nil {
+			entry := AuditEntry{ge.frame, req.Signer, req.Action.Entity(), actionName(req.Action), err.Error()}
+			log.Warn("action unauthorized", "frame", entry.Frame, "signer", entry.Signer.Hex(),
+				"entity", entry.Entity, "action", entry.Action, "reason", entry.Reason)
+			ge.audit.record(entry)
+			ge.metrics.actionUnauthorized()
+			req.report(ActionRejected, err.Error(), 0)
+			return
+		}
+	}
+
+	if oa, ok := req.Action.(originAction); ok {
+		oa.setOrigin(req)
+	}
+
+	if err := req.Action.Execute(); err != nil {
+		ge.metrics.actionRejected()
+		req.report(ActionRejected, err.Error(), 0)
+		return
+	}
+	ge.metrics.actionApplied()
+
+	result := &ActionResult{Id: req.Id, Status: ActionApplied, Frame: ge.frame}
+	if ra, ok := req.Action.(resultAction); ok {
+		result.Data = ra.result()
+	}
+	req.reportResult(result)
+}
+
+// handleTimerActions executes the actions of all timers due at the
+// current world time, in firing order.  Timer actions are authorized like
+// user actions, as the signer may no longer control the entity.
+func (ge *GameEngine) handleTimerActions() {
+	for _, t := range S.Timers.Expire(S.WorldTime) {
+		ge.executeAction(&ActionRequest{
+			Id:      t.Envelope.Id,
+			Action:  t.action,
+			Results: t.results,
+			Signer:  t.Signer,
+		})
+	}
+}
+
+// coastOrbits moves idle orbiting entities elapsed seconds along their
+// orbits, keeping their orbits current with the world time.  Hot entities
+// with force generators are moved by the physics system.
+func (ge *GameEngine) coastOrbits(elapsed float64) {
+	for e, o := range S.Orb {
+		if rf := S.EntFrames[e]; rf != nil && S.HotEnts[rf][e] && len(S.ForceGens[e]) > 0 {
+			continue
+		}
+		S.Orb[e] = o.AtTime(elapsed)
+	}
+}
+
+// postOrbitalEvents posts the orbital events of all orbiting entities that
+// occurred during the last elapsed seconds on the message bus.
+func (ge *GameEngine) postOrbitalEvents(elapsed float64) {
+	for e := range S.Orb {
+		for _, event := range orbitalEventsSince(e, elapsed) {
+			msg, err := json.Marshal(event)
+			if err != nil {
+				log.Error("marshal orbital event", "err", err)
+				continue
+			}
+			S.MsgBus.Post(msg)
+		}
+	}
 }
@@ -0,0 +1,92 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"errors"
+	"testing"
+)
+
+type testAction struct {
+	err      error
+	executed *int
+}
+
+func (a *testAction) Entity() Id { return 0 }
+
+func (a *testAction) Execute() error {
+	*a.executed++
+	return a.err
+}
+
+// panicAction panics when executed, like an action with a bug would.
+type panicAction struct{}
+
+func (a *panicAction) Entity() Id     { return 0 }
+func (a *panicAction) Execute() error { panic("bug") }
+
+func TestHandleUserActionsIsolation(t *testing.T) {
+	ResetState()
+	ge := &GameEngine{actionChan: make(chan *ActionRequest, 2*maxActionsPerLoop)}
+	ge.frame = 7
+	results := make(chan *ActionResult, 2*maxActionsPerLoop)
+
+	executed := 0
+	queue := func(id uint64, a Action) {
+		ge.actionChan <- &ActionRequest{Id: id, Action: a, Results: results, engine: true}
+	}
+	queue(1, &testAction{errors.New("nope"), &executed})
+	queue(2, &panicAction{})
+	n := maxActionsPerLoop + 3
+	for i := 3; i <= n; i++ {
+		queue(uint64(i), &testAction{nil, &executed})
+	}
+
+	ge.handleUserActions()
+	if executed != maxActionsPerLoop-1 {
+		t.Errorf("executed %d actions, want %d", executed, maxActionsPerLoop-1)
+	}
+	if len(ge.actionChan) != n-maxActionsPerLoop {
+		t.Errorf("%d actions left queued, want %d", len(ge.actionChan), n-maxActionsPerLoop)
+	}
+
+	r := <-results
+	if r.Id != 1 || r.Status != ActionRejected || r.Reason != "nope" {
+		t.Errorf("unexpected result %+v", r)
+	}
+	r = <-results
+	if r.Id != 2 || r.Status != ActionRejected || r.Reason == "" {
+		t.Errorf("unexpected result %+v", r)
+	}
+	r = <-results
+	if r.Id != 3 || r.Status != ActionApplied || r.Frame != 7 {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	// remaining actions are not dropped, but applied in the next frame
+	ge.frame++
+	ge.handleUserActions()
+	if executed != n-1 {
+		t.Errorf("executed %d actions, want %d", executed, n-1)
+	}
+
+	m := ge.Metrics()
+	if m.ActionsApplied != uint64(n-2) || m.ActionsRejected != 2 {
+		t.Errorf("unexpected metrics %+v", m)
+	}
+}
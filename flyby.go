@@ -0,0 +1,139 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Gravity assist flybys: bending the velocity relative a planet with the
+// planet's gravity, changing the velocity relative the planet's primary.
+// The flyby is modelled with patched conics: a hyperbola around the planet
+// within its SOI, entered and left with the hyperbolic excess velocity v∞.
+//
+// NOTE: referenced equations, algorithms and examples are from reference
+//       [1] of orbit.go (Curtis).
+
+// Flyby is a planned gravity assist flyby of a planet.
+type Flyby struct {
+	// Periapsis radius, eccentricity and turn angle of the hyperbola
+	Periapsis    float64 `json:"periapsis"`
+	Eccentricity float64 `json:"eccentricity"`
+	TurnAngle    float64 `json:"turnAngle"`
+
+	// B-plane target: B is the vector from the planet to where the
+	// incoming asymptote pierces the plane through the planet normal to
+	// v∞.  BT and BR are its components along T, parallel to the XY plane,
+	// and R = S x T, where S is along the incoming v∞.
+	B  *V3     `json:"b"`
+	BT float64 `json:"bt"`
+	BR float64 `json:"br"`
+
+	// Outgoing v∞ relative the planet
+	VInfOut *V3 `json:"vInfOut"`
+
+	// Velocity relative the primary of the planet's parent frame before
+	// and after the flyby
+	VelIn  *V3 `json:"velIn"`
+	VelOut *V3 `json:"velOut"`
+
+	// Hyperbolic orbit around the planet, at periapsis
+	Orbit *OE `json:"orbit"`
+}
+
+// PlanFlyby plans the flyby of the planet at the origin of ref frame rf,
+// approached with hyperbolic excess velocity vInf and leaving along
+// direction dir, with periapsis passage dt seconds from now.  Flybys
+// through the atmosphere or surface of the planet, or turning less than the
+// planet's SOI allows, are rejected.
+func PlanFlyby(rf *RefFrame, vInf, dir *V3, dt float64) (*Flyby, error) {
+	planet := S.PlanetInFrame(rf)
+	if planet == nil {
+		return nil, errors.New("ref frame does not hold a planet")
+	}
+	v := vInf.Magnitude()
+	if v == 0 || dir.IsZero() {
+		return nil, errors.New("zero hyperbolic excess velocity or direction")
+	}
+	μ := GravitationalConstant * planet.Mass
+
+	sIn := new(V3).MulScalar(vInf, 1/v)
+	sOut := new(V3).Set(dir)
+	sOut.Normalise()
+	δ := math.Acos(math.Max(-1, math.Min(1, sIn.ScalarProduct(sOut))))
+	if δ >= math.Pi-flybyTurnTolerance {
+		return nil, errors.New("flyby cannot reverse the velocity")
+	}
+
+	// Section 8.9: turn angle δ = 2 asin(1/e) and periapsis rp = μ(e-1)/v∞²
+	e := 1 / math.Sin(δ/2)
+	rp := μ / (v * v) * (e - 1)
+
+	minRadius := planet.Radius
+	if planet.Atmosphere != nil {
+		minRadius += planet.Atmosphere.Height
+	}
+	if rp < minRadius {
+		return nil, fmt.Errorf("flyby periapsis %v below atmosphere or surface at %v", rp, minRadius)
+	}
+	if soi := soiRadius(rf); rp > soi || δ < flybyTurnTolerance {
+		return nil, fmt.Errorf("turn angle %v too small for a flyby within the SOI", δ)
+	}
+
+	// the trajectory bends towards the planet: the periapsis lies between
+	// the planet and the incoming asymptote, opposite the turn
+	pHat := new(V3).Sub(sIn, sOut)
+	pHat.Normalise()
+	vHat := new(V3).Add(sIn, sOut)
+	vHat.Normalise()
+	// energy equation of hyperbolas, v² = v∞² + 2μ/r
+	vp := math.Sqrt(v*v + 2*μ/rp)
+	orbit := StateVectorToOrbital(pHat.MulScalar(pHat, rp), vHat.MulScalar(vHat, vp), μ)
+
+	// Section 8.9: aiming radius
+	b := rp * math.Sqrt(1+2*μ/(rp*v*v))
+	bHat := new(V3).AddScaledVector(sIn, sOut.ScalarProduct(sIn))
+	bHat.Sub(bHat, sOut)
+	bHat.Normalise()
+	B := bHat.MulScalar(bHat, b)
+
+	T := new(V3).VectorProduct(sIn, &V3{0, 0, 1})
+	if T.Magnitude() < flybyTurnTolerance {
+		// v∞ along Z: T is parallel to the X axis
+		T = &V3{1, 0, 0}
+	}
+	T.Normalise()
+	R := new(V3).VectorProduct(sIn, T)
+
+	vInfOut := sOut.MulScalar(sOut, v)
+	_, planetVel := rf.LocalState(dt)
+	return &Flyby{
+		Periapsis:    rp,
+		Eccentricity: e,
+		TurnAngle:    δ,
+		B:            B,
+		BT:           B.ScalarProduct(T),
+		BR:           B.ScalarProduct(R),
+		VInfOut:      vInfOut,
+		VelIn:        new(V3).Add(planetVel, vInf),
+		VelOut:       new(V3).Add(planetVel, vInfOut),
+		Orbit:        orbit,
+	}, nil
+}
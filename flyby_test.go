@@ -0,0 +1,108 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+// testFlybyFrame returns the frame of testPlanet on a circular orbit of
+// 1.524 AU around a sun-like star.
+func testFlybyFrame() *RefFrame {
+	ResetState()
+	μSun := GravitationalConstant * solarMass
+	r := 1.524 * aum
+	starRF := &RefFrame{Parent: rootRF}
+	rf := &RefFrame{
+		Parent: starRF,
+		Orbit:  StateVectorToOrbital(&V3{r, 0, 0}, &V3{0, math.Sqrt(μSun / r), 0}, μSun),
+	}
+	planet := testPlanet()
+	planet.Entity = S.NewEntity()
+	S.AddPlanet(planet, rf)
+	return rf
+}
+
+func TestPlanFlyby(t *testing.T) {
+	rf := testFlybyFrame()
+	μ := GravitationalConstant * S.PlanetInFrame(rf).Mass
+	vInf := &V3{3000, 0, 0}
+	δ := DegToRad(30)
+	dir := &V3{math.Cos(δ), math.Sin(δ), 0}
+
+	f, err := PlanFlyby(rf, vInf, dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(f.TurnAngle-δ) > 1e-12 {
+		t.Errorf("turn angle %v, expected %v", f.TurnAngle, δ)
+	}
+	// the hyperbola turns v∞ by δ
+	if math.Abs(2*math.Asin(1/f.Orbit.e)-δ) > 1e-9 || math.Abs(f.Orbit.e-f.Eccentricity) > 1e-9 {
+		t.Errorf("orbit eccentricity %v, expected %v", f.Orbit.e, f.Eccentricity)
+	}
+	if rp := f.Orbit.Altitude(); math.Abs(rp-f.Periapsis) > 1e-6*rp {
+		t.Errorf("orbit periapsis %v, expected %v", rp, f.Periapsis)
+	}
+
+	// far from the planet the trajectory follows the asymptotes
+	far := 1e6
+	posIn, velIn := f.Orbit.AtTime(-far).OrbitalToStateVector()
+	_, velOut := f.Orbit.AtTime(far).OrbitalToStateVector()
+	velIn.Normalise()
+	velOut.Normalise()
+	if velIn.ScalarProduct(&V3{1, 0, 0}) < 1-1e-6 || velOut.ScalarProduct(dir) < 1-1e-6 {
+		t.Errorf("asymptotes %v %v, expected %v %v", velIn.Fmt(), velOut.Fmt(), vInf.Fmt(), dir.Fmt())
+	}
+
+	// the incoming asymptote pierces the B-plane at B
+	b := f.Orbit.h * f.Orbit.h / μ / math.Sqrt(f.Eccentricity*f.Eccentricity-1)
+	if d := new(V3).Sub(&V3{0, posIn.Y, posIn.Z}, f.B).Magnitude(); d > 2e-3*b {
+		t.Errorf("incoming asymptote at %v, expected B %v", posIn.Fmt(), f.B.Fmt())
+	}
+	if math.Abs(f.B.Magnitude()-b) > 1e-6*b || math.Abs(math.Abs(f.BT)-b) > 1e-6*b || math.Abs(f.BR) > 1e-6*b {
+		t.Errorf("B %v (T %v R %v), expected magnitude %v", f.B.Fmt(), f.BT, f.BR, b)
+	}
+
+	// velocities relative the star differ by the change of v∞
+	_, pv := rf.LocalState(0)
+	if d := new(V3).Sub(new(V3).Sub(f.VelIn, pv), vInf).Magnitude(); d > 1e-6 {
+		t.Errorf("incoming velocity %v, planet velocity %v", f.VelIn.Fmt(), pv.Fmt())
+	}
+	gain := new(V3).Sub(f.VelOut, f.VelIn)
+	ex := new(V3).Sub(new(V3).MulScalar(dir, 3000), vInf)
+	if d := new(V3).Sub(gain, ex).Magnitude(); d > 1e-6 {
+		t.Errorf("velocity change %v, expected %v", gain.Fmt(), ex.Fmt())
+	}
+}
+
+func TestPlanFlybyRejected(t *testing.T) {
+	rf := testFlybyFrame()
+	vInf := &V3{3000, 0, 0}
+
+	for _, deg := range []float64{0, 150, 180} {
+		dir := &V3{math.Cos(DegToRad(deg)), math.Sin(DegToRad(deg)), 0}
+		if _, err := PlanFlyby(rf, vInf, dir, 0); err == nil {
+			t.Errorf("expected error for turn of %v degrees", deg)
+		}
+	}
+	if _, err := PlanFlyby(&RefFrame{Parent: rootRF}, vInf, &V3{0, 1, 0}, 0); err == nil {
+		t.Errorf("expected error for frame without planet")
+	}
+}
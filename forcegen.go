@@ -18,6 +18,8 @@
 package tesseract
 
 import (
+	"math"
+
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -30,7 +32,8 @@ import (
 //
 // Force generators must keep track of when they are expired.
 type ForceGen interface {
-	// UpdateForce returns linear force and torque.
+	// UpdateForce returns linear force (N) and torque (N m), averaged over
+	// the elapsed duration; the physics system integrates them over it.
 	// Zero force/torque should be returned as nil.
 	// UpdateForce is called by the physics system once per game frame.
 	UpdateForce(e Id, duration float64) (*V3, *V3)
@@ -72,14 +75,12 @@ type ThrustForceGen struct {
 
 func (t *ThrustForceGen) UpdateForce(e Id, elapsed float64) (*V3, *V3) {
 	//log.Debug("ThrustForceGen.UpdateForce", "t", t.thrust)
-	var f float64
-	if t.timeLeft > elapsed {
-		f = t.thrust * elapsed
-		t.timeLeft -= elapsed
-	} else {
-		f = t.thrust * t.timeLeft
-		t.timeLeft = 0
+	if elapsed <= 0 {
+		return nil, nil
 	}
+	// average over elapsed of the thrust for the time left
+	f := t.thrust * math.Min(elapsed, t.timeLeft) / elapsed
+	t.timeLeft = math.Max(0, t.timeLeft-elapsed)
 
 	fv := S.Ori[e].ForwardVector()
 	return fv.MulScalar(fv, f), nil
@@ -97,16 +98,15 @@ type TurnForceGen struct {
 }
 
 func (t *TurnForceGen) UpdateForce(e Id, elapsed float64) (*V3, *V3) {
-	tt := S.Rot[e].T.Transform(t.torque)
-
-	if t.timeLeft > elapsed {
-		t.timeLeft -= elapsed
-		return nil, new(V3).MulScalar(tt, elapsed)
+	if elapsed <= 0 {
+		return nil, nil
 	}
+	d := math.Min(elapsed, t.timeLeft)
+	tt := S.Ori[e].Rotate(cmgTorque(e, t.torque, d))
+	t.timeLeft = math.Max(0, t.timeLeft-elapsed)
 
-	resTorque := new(V3).MulScalar(tt, t.timeLeft)
-	t.timeLeft = 0
-	return nil, resTorque
+	// average over elapsed of the torque for the time left
+	return nil, tt.MulScalar(tt, d/elapsed)
 }
 
 func (t *TurnForceGen) IsExpired() bool {
@@ -78,6 +78,7 @@ func (s *Sector) addStarFixed(newStar *Star, pos *V3) {
 		Pos:         pos,
 		Orbit:       nil,
 		Orientation: nil, // TODO
+		Radius:      starSystemRadius,
 	}
 	S.EntFrames[newStar.Entity] = newStarRF
 
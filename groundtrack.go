@@ -0,0 +1,76 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Ground tracks: the path of an orbiter's sub-satellite point over the
+// surface of the rotating planet it orbits, e.g. for reconnaissance and
+// landing site planning.
+
+// GroundTrackPoint is a sub-satellite point of a ground track.
+type GroundTrackPoint struct {
+	Time float64 `json:"time"` // seconds from the orbit's current position
+
+	// Planet-fixed latitude and longitude in radians and altitude above
+	// the surface
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+	Alt float64 `json:"alt"`
+}
+
+// GroundTrack returns the ground track of the orbiter at world time now on
+// orbit o around the planet over the next periods orbital periods, sampled
+// at groundTrackSamplesPerOrbit evenly spaced points in time per period.
+// The first point is the current sub-satellite point.
+func (p *Planet) GroundTrack(o *OE, now, periods float64) ([]*GroundTrackPoint, error) {
+	if o.e >= 1 {
+		return nil, errors.New("open orbit has no period")
+	}
+	if periods <= 0 || periods > groundTrackMaxPeriods {
+		return nil, fmt.Errorf("invalid number of periods %v", periods)
+	}
+
+	n := int(periods * groundTrackSamplesPerOrbit)
+	if n < 1 {
+		// at least the start and end points, however short the track
+		n = 1
+	}
+	step := periods * o.Period() / float64(n)
+	track := make([]*GroundTrackPoint, 0, n+1)
+	for i := 0; i <= n; i++ {
+		t := float64(i) * step
+		pos, _ := o.AtTime(t).OrbitalToStateVector()
+		lat, lon, alt := p.CartesianToGeodetic(p.FixedPosition(pos, now+t))
+		track = append(track, &GroundTrackPoint{t, lat, lon, alt})
+	}
+	return track, nil
+}
+
+// EntityGroundTrack returns the ground track of the entity over the planet
+// it orbits for the next periods orbital periods.
+func EntityGroundTrack(e Id, periods float64) ([]*GroundTrackPoint, error) {
+	planet := S.PlanetInFrame(S.EntFrames[e])
+	if S.Orb[e] == nil || planet == nil {
+		return nil, fmt.Errorf("entity %v is not orbiting a planet", e)
+	}
+	return planet.GroundTrack(S.Orb[e], S.WorldTime, periods)
+}
@@ -0,0 +1,96 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGroundTrack(t *testing.T) {
+	p := testPlanet()
+	p.AxialTilt = 0
+	μ := GravitationalConstant * p.Mass
+	r := p.Radius + 400e3
+	i := 0.5
+
+	v := math.Sqrt(μ / r)
+	o := StateVectorToOrbital(&V3{r, 0, 0}, &V3{0, v * math.Cos(i), v * math.Sin(i)}, μ)
+
+	track, err := p.GroundTrack(o, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(track) != 2*groundTrackSamplesPerOrbit+1 {
+		t.Fatalf("got %v points, expected %v", len(track), 2*groundTrackSamplesPerOrbit+1)
+	}
+
+	// the track stays within the inclination and altitude of the orbit
+	maxLat := 0.0
+	for _, pt := range track {
+		maxLat = math.Max(maxLat, math.Abs(pt.Lat))
+		if math.Abs(pt.Alt-400e3) > 1e-3 {
+			t.Errorf("point at %v altitude %v, expected 400 km", pt.Time, pt.Alt)
+		}
+	}
+	if maxLat > i+1e-9 || maxLat < i-1e-3 {
+		t.Errorf("max latitude %v, expected %v", maxLat, i)
+	}
+
+	// the planet rotates east under the orbit: each period the track
+	// shifts west
+	period := o.Period()
+	ex := -twoPi * period / p.RotationPeriod
+	for j, n := range []int{groundTrackSamplesPerOrbit, 2 * groundTrackSamplesPerOrbit} {
+		pt := track[n]
+		if math.Abs(pt.Time-float64(j+1)*period) > 1e-6 || math.Abs(pt.Lat) > 1e-6 {
+			t.Errorf("point %v at %v latitude %v, expected equator crossing", n, pt.Time, pt.Lat)
+		}
+		if d := math.Remainder(pt.Lon-track[0].Lon-float64(j+1)*ex, twoPi); math.Abs(d) > 1e-6 {
+			t.Errorf("point %v longitude %v, expected %v", n, pt.Lon, track[0].Lon+float64(j+1)*ex)
+		}
+	}
+
+	// a track shorter than a sample interval has its start and end points
+	short, err := p.GroundTrack(o, 0, 0.1/groundTrackSamplesPerOrbit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(short) != 2 {
+		t.Fatalf("got %v points for short track, expected 2", len(short))
+	}
+	for _, pt := range short {
+		if math.IsNaN(pt.Time) || math.IsNaN(pt.Lat) || math.IsNaN(pt.Lon) || math.IsNaN(pt.Alt) {
+			t.Errorf("short track point %+v is NaN", pt)
+		}
+	}
+	if d := short[1].Time - 0.1*period/groundTrackSamplesPerOrbit; math.Abs(d) > 1e-9 {
+		t.Errorf("short track ends at %v, expected %v", short[1].Time, 0.1*period/groundTrackSamplesPerOrbit)
+	}
+
+	if _, err := p.GroundTrack(o, 0, 0); err == nil {
+		t.Errorf("expected error for zero periods")
+	}
+	if _, err := p.GroundTrack(o, 0, -1); err == nil {
+		t.Errorf("expected error for negative periods")
+	}
+	hyperbolic := StateVectorToOrbital(&V3{r, 0, 0}, &V3{0, 2 * v, 0}, μ)
+	if _, err := p.GroundTrack(hyperbolic, 0, 1); err == nil {
+		t.Errorf("expected error for open orbit")
+	}
+}
@@ -17,51 +17,164 @@
 */
 package tesseract
 
-import "github.com/ethereum/go-ethereum/log"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ethereum/go-ethereum/log"
+)
 
 //
 // The hyperdrive system implements travel through hyperspace.
 //
+// A jump has three phases, each starting with an event posted on the
+// message bus:
+//
+// 1. spool-up: the jump drive charges (see jumpdrive.go) while the ship
+//    stays in its ref frame.  The jump can still be aborted.
+// 2. transit: the ship leaves its ref frame for the root frame, moving in a
+//    straight line in galactic coordinates towards a target star, towards
+//    galactic coordinates or along a heading.  The ship can drop out of
+//    hyperspace at any time.
+// 3. arrival: the ship enters the ref frame of the target star on its
+//    DefaultOrbit.  Ships arriving at galactic coordinates or dropping out
+//    enter an interstellar ref frame (see interstellar.go).
+//
 type Hyperdrive struct {
 }
 
+const (
+	EventHyperdriveSpool       = "hyperdrive spool"
+	EventHyperdriveTransit     = "hyperdrive transit"
+	EventHyperdriveArrival     = "hyperdrive arrival"
+	EventHyperdriveAbort       = "hyperdrive abort"
+	EventHyperdriveDropout     = "hyperdrive dropout"
+	EventHyperdriveInterdicted = "hyperdrive interdicted"
+)
+
+// HyperdriveEvent is posted on the message bus when a ship in hyperdrive
+// enters a new phase of its jump.
+type HyperdriveEvent struct {
+	Entity Id      `json:"entity"`
+	Event  string  `json:"event"`
+	Time   float64 `json:"time"` // world time
+	Target Id      `json:"target,omitempty"`
+	Pos    *V3     `json:"pos"` // galactic grid units
+
+	// Interdictor of interdicted ships
+	By Id `json:"by,omitempty"`
+}
+
 // Data for an instance of one ship in hyperdrive
 type Hyperspace struct {
 	// Start position in galactic grid units (see galaxy.go)
 	Start *V3
 
-	// Target the hyperdrive is locked onto
-	// TODO: support non-star targets
-	// TODO: support hyperdrive in arbitrary directions without target lock
+	// Target star the hyperdrive is locked onto, if any
 	Target *Star
 
-	// Time when reaching target
+	// Destination in galactic grid units, or nil if travelling along
+	// Heading (unit vector) until dropping out
+	Dest    *V3
+	Heading *V3
+
+	// Time when spool-up ends and transit begins
+	Departure float64
+
+	// Time when reaching target; +Inf if travelling along a heading
 	TargetTime float64
 
 	// Multiple of speed of light in vacuum
 	Speed float64
 
+	// If in transit, i.e. the ship left its ref frame
+	Transit bool
+
 	// If exited by user action
 	Exited bool
+
+	// Interdictor: the entity pulling the ship out of hyperspace, or the
+	// star whose system cuts the route short (see interdiction.go)
+	Interdictor Id
+}
+
+// NewHyperspace returns a jump from start, in galactic grid units, to the
+// target star, departing at world time departure and arriving at tTime.
+func NewHyperspace(start *V3, target *Star, departure, tTime float64) (*Hyperspace, error) {
+	hs, err := NewHyperspaceTo(start, S.Pos[target.Entity], departure, tTime)
+	if err != nil {
+		return nil, err
+	}
+	hs.Target = target
+	return hs, nil
 }
 
-func NewHyperspace(start *V3, target *Star, wTime, tTime float64) *Hyperspace {
-	if wTime >= tTime {
-		panic("target time must be in the future")
+// NewHyperspaceTo returns a jump from start to dest, both in galactic grid
+// units, departing at world time departure and arriving at tTime.
+func NewHyperspaceTo(start, dest *V3, departure, tTime float64) (*Hyperspace, error) {
+	if tTime <= departure {
+		return nil, fmt.Errorf("arrival %v before end of spool-up at %v", tTime, departure)
 	}
-	travelSeconds := tTime - wTime
+	travelSeconds := tTime - departure
 
-	log.Debug("debug", "d", target.Entity)
-	dist := new(V3).Sub(start, S.Pos[target.Entity]).Magnitude()
+	dist := new(V3).Sub(start, dest).Magnitude()
 	lightSeconds := ((dist * gridUnit) * aum) / speedOfLight
 	speed := lightSeconds / travelSeconds
 	return &Hyperspace{
-		start,
-		target,
-		tTime,
-		speed,
-		false,
+		Start:      start,
+		Dest:       new(V3).Set(dest),
+		Departure:  departure,
+		TargetTime: tTime,
+		Speed:      speed,
+	}, nil
+}
+
+// NewHyperspaceHeading returns a jump from start, in galactic grid units,
+// along heading at speed, a multiple of the speed of light, departing at
+// world time departure.  The ship travels until dropping out.
+func NewHyperspaceHeading(start, heading *V3, speed, departure float64) (*Hyperspace, error) {
+	if heading.IsZero() {
+		return nil, errors.New("zero heading")
+	}
+	if !(speed > 0) || math.IsInf(speed, 0) {
+		return nil, fmt.Errorf("invalid hyperdrive speed %v", speed)
+	}
+	h := new(V3).Set(heading)
+	h.Normalise()
+	return &Hyperspace{
+		Start:      start,
+		Heading:    h,
+		Departure:  departure,
+		TargetTime: math.Inf(1),
+		Speed:      speed,
+	}, nil
+}
+
+// Position returns the position in galactic grid units at world time
+// wTime.  Jumps to a destination are interpolated between start and
+// destination over the transit.
+func (hs *Hyperspace) Position(wTime float64) *V3 {
+	if hs.Dest == nil {
+		t := math.Max(0, wTime-hs.Departure)
+		dist := hs.Speed * speedOfLight * t / (gridUnit * aum)
+		return new(V3).Set(hs.Start).AddScaledVector(hs.Heading, dist)
 	}
+	f := (wTime - hs.Departure) / (hs.TargetTime - hs.Departure)
+	f = math.Max(0, math.Min(1, f))
+	pos := new(V3).Sub(hs.Dest, hs.Start)
+	pos.MulScalar(pos, f)
+	return pos.Add(pos, hs.Start)
+}
+
+// galacticPos returns the position of the entity in galactic grid units.
+func galacticPos(e Id) *V3 {
+	if S.EntFrames[e].IsRoot() {
+		return new(V3).Set(S.Pos[e])
+	}
+	pos := entityPosIn(e, rootRF, 0)
+	return pos.MulScalar(pos, 1/(gridUnit*aum))
 }
 
 //
@@ -85,28 +198,183 @@ func (hd *Hyperdrive) Update(wTime, elapsed float64, rf *RefFrame) error {
 
 func updateHyperdrive(wTime, elapsed float64, rf *RefFrame, e Id) {
 	hs := S.Hyperspace[e]
-	if hs.TargetTime < wTime {
-		// exit hyperdrive at destination, even if user sent exit action
-		// since last update
 
-		// TODO: how to retrieve / instantiate target ref frame
-		//to := &RefFrame{}
-		//updateEntityRefFrame(e, rf)
+	if hs.Exited && !hs.Transit {
+		postHyperdriveEvent(e, EventHyperdriveAbort, wTime)
+		delete(S.Hyperspace, e)
+		return
+	}
+	// TODO: galactic coll det/resp - interstellar clouds, etc
+
+	departing := !hs.Transit
+	if departing {
+		if wTime < hs.Departure {
+			return
+		}
+		hs.Transit = true
+		delete(S.Orb, e)
+		delete(S.Vel, e)
+		moveEntity(e, rootRF)
+		S.SetHot(e, rootRF)
+	}
 
+	// entities in the root frame are positioned in galactic grid units,
+	// like the star system frames
+	S.Pos[e] = hs.Position(wTime)
+	if departing {
+		postHyperdriveEvent(e, EventHyperdriveTransit, wTime)
+	}
+
+	from := hs.Position(wTime - elapsed)
+	if by, at := interdictor(e, from, S.Pos[e]); by != 0 {
+		hs.Interdictor = by
+		S.Pos[e] = at
+		postHyperdriveEvent(e, EventHyperdriveInterdicted, wTime)
+		dropOut(e, wTime)
+		return
+	}
+
+	switch {
+	case hs.TargetTime <= wTime && hs.Target != nil:
+		// exit hyperdrive at destination, even if user sent exit action
+		// since last update
+		to := S.EntFrames[hs.Target.Entity]
+		postHyperdriveEvent(e, EventHyperdriveArrival, wTime)
+		endJump(e, wTime)
+		delete(S.Pos, e)
+		moveEntity(e, to)
 		S.Orb[e] = hs.Target.DefaultOrbit()
+		S.SetIdle(e, to, wTime)
 		delete(S.Hyperspace, e)
+	case hs.TargetTime <= wTime && hs.Interdictor != 0:
+		postHyperdriveEvent(e, EventHyperdriveInterdicted, wTime)
+		dropOut(e, wTime)
+	case hs.TargetTime <= wTime:
+		postHyperdriveEvent(e, EventHyperdriveArrival, wTime)
+		dropOut(e, wTime)
+	case hs.Exited:
+		postHyperdriveEvent(e, EventHyperdriveDropout, wTime)
+		dropOut(e, wTime)
 	}
+}
 
-	if hs.Exited {
-		panic("todo")
-		// TODO: check if local frame exists
-		// TODO: disallow hyperdrive through ref frames like star systems
-		// TODO: galactic coll det/resp - interstellar clouds, etc
-		//delete(S.HSC, e)
-		//continue
-	}
+// dropOut moves the entity from hyperspace into the interstellar ref frame
+// at its position, at rest relative the frame.
+func dropOut(e Id, wTime float64) {
+	endJump(e, wTime)
+	rf, pos := interstellarFrame(S.Pos[e])
+	moveEntity(e, rf)
+	S.Pos[e] = pos
+	S.Vel[e] = new(V3)
+	S.SetIdle(e, rf, wTime)
+	delete(S.Hyperspace, e)
 }
 
 func (hd *Hyperdrive) IsHotPostUpdate(e Id) bool {
 	return S.Hyperspace[e] != nil
 }
+
+func postHyperdriveEvent(e Id, event string, wTime float64) {
+	hs := S.Hyperspace[e]
+	var target Id
+	if hs.Target != nil {
+		target = hs.Target.Entity
+	}
+	msg, err := json.Marshal(&HyperdriveEvent{e, event, wTime, target, galacticPos(e), hs.Interdictor})
+	if err != nil {
+		log.Error("marshal hyperdrive event", "err", err)
+		return
+	}
+	S.MsgBus.Post(msg)
+}
+
+// ActionHyperdrive engages the hyperdrive of the ship towards the target
+// star or galactic coordinates dest, arriving at world time at, or along
+// heading at speed, a multiple of the speed of light.
+type ActionHyperdrive struct {
+	entity Id
+	target Id
+	dest   *V3
+	at     float64
+
+	heading *V3
+	speed   float64
+}
+
+func (a *ActionHyperdrive) Entity() Id { return a.entity }
+
+func (a *ActionHyperdrive) Execute() error {
+	drive := S.JumpDrive[a.entity]
+	if drive == nil {
+		return fmt.Errorf("entity %v has no jump drive", a.entity)
+	}
+	if S.Hyperspace[a.entity] != nil {
+		return fmt.Errorf("entity %v already in hyperdrive", a.entity)
+	}
+	rf := S.EntFrames[a.entity]
+	if len(S.ForceGens[a.entity]) > 0 {
+		return fmt.Errorf("entity %v is maneuvering", a.entity)
+	}
+	if well := gravityWell(a.entity); well != 0 {
+		return fmt.Errorf("entity %v within gravity well of %v", a.entity, well)
+	}
+
+	var hs *Hyperspace
+	var err error
+	m := *S.Mass[a.entity]
+	start := galacticPos(a.entity)
+	departure := drive.departure(m, S.WorldTime)
+	switch {
+	case a.heading != nil:
+		hs, err = NewHyperspaceHeading(start, a.heading, a.speed, departure)
+	case a.dest != nil:
+		if err := drive.checkArrival(m, start, a.dest, a.at, S.WorldTime); err != nil {
+			return err
+		}
+		hs, err = NewHyperspaceTo(start, a.dest, departure, a.at)
+	default:
+		target := S.StarsById[a.target]
+		if target == nil {
+			return fmt.Errorf("unknown target star %v", a.target)
+		}
+		if systemStar(rf) == target {
+			return errors.New("already in the target star system")
+		}
+		if err := drive.checkArrival(m, start, S.Pos[target.Entity], a.at, S.WorldTime); err != nil {
+			return err
+		}
+		hs, err = NewHyperspace(start, target, departure, a.at)
+	}
+	if err != nil {
+		return err
+	}
+	if err := drive.checkJump(m, hs, S.WorldTime); err != nil {
+		return err
+	}
+	if hs.Dest == nil {
+		drive.limitRange(m, hs)
+	}
+	hs.cutShort(systemStar(rf))
+	delete(S.Interdictors, a.entity)
+	S.Hyperspace[a.entity] = hs
+	S.SetHot(a.entity, rf)
+	postHyperdriveEvent(a.entity, EventHyperdriveSpool, S.WorldTime)
+	return nil
+}
+
+// ActionHyperdriveExit aborts the jump of the ship during spool-up, or
+// drops the ship out of hyperspace during transit.
+type ActionHyperdriveExit struct {
+	entity Id
+}
+
+func (a *ActionHyperdriveExit) Entity() Id { return a.entity }
+
+func (a *ActionHyperdriveExit) Execute() error {
+	hs := S.Hyperspace[a.entity]
+	if hs == nil {
+		return fmt.Errorf("entity %v not in hyperdrive", a.entity)
+	}
+	hs.Exited = true
+	return nil
+}
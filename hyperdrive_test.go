@@ -0,0 +1,246 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// devHyperdriveWorld returns the dev ship, moved out of the gravity wells
+// of the dev system onto a 5 AU orbit of the dev star, and a second star
+// two grid units from the dev star.
+func devHyperdriveWorld() (Id, *Star) {
+	e := NewDevWorld(1, common.Address{})
+	home := systemStar(S.EntFrames[e])
+	homeRF := S.EntFrames[home.Entity]
+	moveEntity(e, homeRF)
+	μ := GravitationalConstant * home.Body.Mass * solarMass
+	S.Orb[e] = StateVectorToOrbital(&V3{5 * aum, 0, 0}, &V3{0, math.Sqrt(μ / (5 * aum)), 0}, μ)
+	S.SetIdle(e, homeRF, 0)
+
+	pos := new(V3).Add(S.Pos[home.Entity], &V3{0, 0, 2})
+	star := NewStar(0.8)
+	star.Entity = S.NewEntity()
+	GetSector(pos).addStarFixed(star, pos)
+	return e, star
+}
+
+func nextHyperdriveEvent(t *testing.T, bus <-chan []byte) *HyperdriveEvent {
+	t.Helper()
+	select {
+	case msg := <-bus:
+		ev := new(HyperdriveEvent)
+		if err := json.Unmarshal(msg, ev); err != nil {
+			t.Fatal(err)
+		}
+		return ev
+	default:
+		t.Fatal("no hyperdrive event")
+		return nil
+	}
+}
+
+func TestHyperdriveJump(t *testing.T) {
+	e, star := devHyperdriveWorld()
+	home := S.EntFrames[e]
+	ge := &GameEngine{systems: []System{&Physics{}, &Hyperdrive{}}}
+	bus := S.MsgBus.Subscribe()
+	frame := func() {
+		S.WorldTime += 1
+		ge.coastOrbits(1)
+		if err := ge.update(S.WorldTime, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	charge := S.JumpDrive[e].ChargeTime(*S.Mass[e])
+	for _, a := range []*ActionHyperdrive{
+		{entity: e, target: systemStar(home).Entity, at: 100},
+		{entity: e, target: 404, at: 100},
+		{entity: e, target: star.Entity, at: charge},
+	} {
+		if err := a.Execute(); err == nil {
+			t.Errorf("expected error for target %v at %v", a.target, a.at)
+		}
+	}
+
+	arrival := math.Ceil(charge) + 100
+	if err := (&ActionHyperdrive{entity: e, target: star.Entity, at: arrival}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if ev := nextHyperdriveEvent(t, bus); ev.Event != EventHyperdriveSpool || ev.Target != star.Entity {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	start := new(V3).Set(S.Hyperspace[e].Start)
+
+	// spooling ships keep orbiting in their ref frame
+	θ := S.Orb[e].θ
+	for S.WorldTime < math.Ceil(charge)-1 {
+		frame()
+	}
+	if S.EntFrames[e] != home || !S.HotEnts[home][e] || S.Orb[e].θ == θ {
+		t.Errorf("spooling ship left its orbit")
+	}
+
+	frame()
+	if S.EntFrames[e] != rootRF || S.Orb[e] != nil {
+		t.Fatalf("ship not in transit")
+	}
+	if ev := nextHyperdriveEvent(t, bus); ev.Event != EventHyperdriveTransit || ev.Time != math.Ceil(charge) {
+		t.Errorf("unexpected event %+v", ev)
+	}
+	// halfway there halfway through the transit
+	for S.WorldTime < (charge+arrival)/2 {
+		frame()
+	}
+	f := (S.WorldTime - charge) / (arrival - charge)
+	mid := new(V3).Sub(S.Pos[star.Entity], start)
+	mid.MulScalar(mid, f).Add(mid, start)
+	if d := new(V3).Sub(S.Pos[e], mid).Magnitude(); d > 1e-9 {
+		t.Errorf("at %v, expected %v", S.Pos[e].Fmt(), mid.Fmt())
+	}
+
+	for S.WorldTime < arrival {
+		frame()
+	}
+	to := S.EntFrames[star.Entity]
+	if S.EntFrames[e] != to || !S.IdleEnts[to][e] || S.HotEnts[rootRF][e] || S.Hyperspace[e] != nil || S.Pos[e] != nil {
+		t.Fatalf("ship not arrived in the target star system")
+	}
+	if r := S.Orb[e].Altitude(); math.Abs(r-star.DefaultOrbit().Altitude()) > 1e-3 {
+		t.Errorf("arrived at radius %v", r)
+	}
+	if ev := nextHyperdriveEvent(t, bus); ev.Event != EventHyperdriveArrival || ev.Time != arrival {
+		t.Errorf("unexpected event %+v", ev)
+	}
+}
+
+func TestHyperdriveAbort(t *testing.T) {
+	e, star := devHyperdriveWorld()
+	home := S.EntFrames[e]
+	ge := &GameEngine{systems: []System{&Physics{}, &Hyperdrive{}}}
+	bus := S.MsgBus.Subscribe()
+
+	if err := (&ActionHyperdriveExit{e}).Execute(); err == nil {
+		t.Errorf("expected error for ship not in hyperdrive")
+	}
+	if err := (&ActionHyperdrive{entity: e, target: star.Entity, at: 1000}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&ActionHyperdrive{entity: e, target: star.Entity, at: 1000}).Execute(); err == nil {
+		t.Errorf("expected error for ship already in hyperdrive")
+	}
+	if err := (&ActionHyperdriveExit{e}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	S.WorldTime += 1
+	if err := ge.update(S.WorldTime, 1); err != nil {
+		t.Fatal(err)
+	}
+	if S.Hyperspace[e] != nil || S.EntFrames[e] != home || !S.IdleEnts[home][e] {
+		t.Errorf("jump not aborted")
+	}
+	nextHyperdriveEvent(t, bus)
+	if ev := nextHyperdriveEvent(t, bus); ev.Event != EventHyperdriveAbort {
+		t.Errorf("unexpected event %+v", ev)
+	}
+}
+
+func TestHyperdriveDropout(t *testing.T) {
+	e, _ := devHyperdriveWorld()
+	home := S.EntFrames[e]
+	e2 := DevNewShip()
+	S.EntFrames[e2] = home
+	S.Orb[e2] = S.Orb[e].AtTime(0)
+	S.SetIdle(e2, home, 0)
+
+	ge := &GameEngine{systems: []System{&Physics{}, &Hyperdrive{}}}
+	bus := S.MsgBus.Subscribe()
+	frame := func() {
+		S.WorldTime += 1
+		ge.coastOrbits(1)
+		if err := ge.update(S.WorldTime, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the max speed of the jump drive along the Y axis
+	speed := S.JumpDrive[e].Class.MaxSpeedCap()
+	heading := &ActionHyperdrive{entity: e, heading: &V3{0, 2, 0}, speed: speed}
+	if err := heading.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	start := new(V3).Set(S.Hyperspace[e].Start)
+	departure := S.Hyperspace[e].Departure
+	for S.WorldTime < departure+10 {
+		frame()
+	}
+	if err := (&ActionHyperdriveExit{e}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	frame()
+
+	rf := S.EntFrames[e]
+	if !S.Interstellar[rf] || rf.Parent != rootRF || !S.IdleEnts[rf][e] || S.Hyperspace[e] != nil {
+		t.Fatalf("ship not dropped out into an interstellar frame")
+	}
+	dist := speed * speedOfLight * (S.WorldTime - departure) / (gridUnit * aum)
+	ex := new(V3).Set(start).AddScaledVector(&V3{0, 1, 0}, dist)
+	if d := new(V3).Sub(rf.Pos, ex).Magnitude(); d > 1e-9 || !S.Pos[e].IsZero() {
+		t.Errorf("dropped out at %v, expected %v", rf.Pos.Fmt(), ex.Fmt())
+	}
+	nextHyperdriveEvent(t, bus)
+	nextHyperdriveEvent(t, bus)
+	if ev := nextHyperdriveEvent(t, bus); ev.Event != EventHyperdriveDropout || ev.Target != 0 {
+		t.Errorf("unexpected event %+v", ev)
+	}
+
+	// ships arriving close to the interstellar frame join it
+	dest := new(V3).Set(rf.Pos).AddScaledVector(&V3{1, 0, 0}, 0.5/gridUnit)
+	charge := S.JumpDrive[e2].ChargeTime(*S.Mass[e2])
+	jump := &ActionHyperdrive{entity: e2, dest: dest, at: S.WorldTime + charge + 100}
+	if err := jump.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	for S.Hyperspace[e2] != nil {
+		frame()
+	}
+	if S.EntFrames[e2] != rf || math.Abs(S.Pos[e2].X-0.5*aum) > 1 || len(S.Interstellar) != 1 {
+		t.Errorf("ship arrived at %v in frame %v", S.Pos[e2].Fmt(), S.EntFrames[e2].Pos.Fmt())
+	}
+
+	// the frame is removed once the last ship leaves
+	for _, ship := range []Id{e, e2} {
+		for S.WorldTime < S.JumpDrive[ship].ReadyAt {
+			frame()
+		}
+		if err := (&ActionHyperdrive{entity: ship, heading: &V3{1, 0, 0}, speed: 1}).Execute(); err != nil {
+			t.Fatal(err)
+		}
+		for S.EntFrames[ship] != rootRF {
+			frame()
+		}
+		if left := S.Interstellar[rf]; left != (ship == e) {
+			t.Errorf("interstellar frame kept %v after ship %v left", left, ship)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"fmt"
+)
+
+// Impact prediction: whether, when and where a trajectory enters the
+// atmosphere of the planet it orbits and hits its surface.  Trajectories
+// are Keplerian; drag in the atmosphere is not modelled, so the actual
+// impact is earlier and short of the predicted one.
+
+// SurfaceCrossing is the crossing of a sphere around a planet, the top of
+// its atmosphere or its surface, by a trajectory.
+type SurfaceCrossing struct {
+	Time float64 `json:"time"` // seconds from the orbit's current position
+
+	// Position and velocity relative the planet in ref frame coordinates
+	Pos *V3 `json:"pos"`
+	Vel *V3 `json:"vel"`
+
+	// Planet-fixed latitude and longitude in radians
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// ImpactPrediction holds the predicted atmosphere entry (interface) and
+// impact of a trajectory.  Either is nil if the trajectory does not reach
+// it before leaving the planet for good or, for closed orbits, within one
+// period.
+type ImpactPrediction struct {
+	Interface *SurfaceCrossing `json:"interface,omitempty"`
+	Impact    *SurfaceCrossing `json:"impact,omitempty"`
+}
+
+// PredictImpact returns the predicted atmosphere entry and impact of the
+// orbiter at world time now on orbit o around the planet.  An atmosphere
+// entry is only predicted if it comes before the impact.
+func (p *Planet) PredictImpact(o *OE, now float64) *ImpactPrediction {
+	pred := &ImpactPrediction{Impact: p.crossing(o, p.Radius, now)}
+	if p.Atmosphere != nil {
+		entry := p.crossing(o, p.Radius+p.Atmosphere.Height, now)
+		if entry != nil && (pred.Impact == nil || entry.Time <= pred.Impact.Time) {
+			pred.Interface = entry
+		}
+	}
+	return pred
+}
+
+// crossing returns the next inbound crossing of radius r by the orbiter
+// at world time now on orbit o, or nil if there is none.
+func (p *Planet) crossing(o *OE, r, now float64) *SurfaceCrossing {
+	dt, err := o.TimeToRadius(r, false)
+	if err != nil {
+		return nil
+	}
+	pos, vel := o.AtTime(dt).OrbitalToStateVector()
+	lat, lon, _ := p.CartesianToGeodetic(p.FixedPosition(pos, now+dt))
+	return &SurfaceCrossing{dt, pos, vel, lat, lon}
+}
+
+// EntityImpact returns the predicted atmosphere entry and impact of the
+// entity on the planet it orbits.
+func EntityImpact(e Id) (*ImpactPrediction, error) {
+	planet := S.PlanetInFrame(S.EntFrames[e])
+	if S.Orb[e] == nil || planet == nil {
+		return nil, fmt.Errorf("entity %v is not orbiting a planet", e)
+	}
+	return planet.PredictImpact(S.Orb[e], S.WorldTime), nil
+}
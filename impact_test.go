@@ -0,0 +1,73 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPredictImpact(t *testing.T) {
+	p := testPlanet()
+	μ := GravitationalConstant * p.Mass
+	R, H := p.Radius, p.Atmosphere.Height
+
+	// from apoapsis 400 km up to periapsis 100 km below the surface
+	orbit := func(rp, ra float64) *OE {
+		o := StateVectorToOrbital(&V3{rp, 0, 0}, &V3{0, apseSpeed(μ, rp, ra), 0}, μ)
+		return o.AtTime(o.Period() / 2)
+	}
+	o := orbit(R-100e3, R+400e3)
+	now := 1000.0
+
+	pred := p.PredictImpact(o, now)
+	if pred.Impact == nil || pred.Interface == nil {
+		t.Fatalf("expected impact and atmosphere entry, got %+v", pred)
+	}
+	for _, c := range []struct {
+		crossing *SurfaceCrossing
+		r        float64
+	}{{pred.Interface, R + H}, {pred.Impact, R}} {
+		pos, _ := o.AtTime(c.crossing.Time).OrbitalToStateVector()
+		if math.Abs(pos.Magnitude()-c.r) > 1 || new(V3).Sub(pos, c.crossing.Pos).Magnitude() > 1e-6 {
+			t.Errorf("crossing at %v, expected radius %v", c.crossing.Pos.Fmt(), c.r)
+		}
+		if c.crossing.Pos.ScalarProduct(c.crossing.Vel) >= 0 {
+			t.Errorf("crossing at radius %v outbound", c.r)
+		}
+		lat, lon, _ := p.CartesianToGeodetic(p.FixedPosition(c.crossing.Pos, now+c.crossing.Time))
+		if lat != c.crossing.Lat || lon != c.crossing.Lon {
+			t.Errorf("crossing at %v %v, expected %v %v", c.crossing.Lat, c.crossing.Lon, lat, lon)
+		}
+	}
+	if pred.Interface.Time >= pred.Impact.Time {
+		t.Errorf("atmosphere entry at %v after impact at %v", pred.Interface.Time, pred.Impact.Time)
+	}
+
+	// grazing the atmosphere
+	pred = p.PredictImpact(orbit(R+H/2, R+400e3), now)
+	if pred.Interface == nil || pred.Impact != nil {
+		t.Errorf("expected atmosphere entry only, got %+v", pred)
+	}
+
+	// clear of the atmosphere
+	pred = p.PredictImpact(orbit(R+2*H, R+400e3), now)
+	if pred.Interface != nil || pred.Impact != nil {
+		t.Errorf("expected no crossings, got %+v", pred)
+	}
+}
@@ -0,0 +1,171 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"fmt"
+	"math"
+)
+
+// Hyperdrive safety constraints and interdiction.  Hyperdrives cannot
+// engage within the gravity wells of bodies, and hyperspace routes cannot
+// cross star systems: a route through a star system other than the
+// origin or target system is cut short at the system's boundary.
+// Interdictors pull hyperspace travelers passing within interdictionRange
+// out of hyperspace.
+
+// gravityWellRadius returns the radius of the gravity well of the planet or
+// moon at the origin of ref frame rf, which blocks hyperdrive engagement:
+// its SOI, or Hill radius if hyperdriveHillWells, times
+// hyperdriveWellMultiple.  Bodies without a primary have no gravity well.
+func gravityWellRadius(rf *RefFrame) float64 {
+	r := soiRadius(rf)
+	if hyperdriveHillWells {
+		r = hillRadius(rf)
+	}
+	if math.IsInf(r, 1) {
+		return 0
+	}
+	return r * hyperdriveWellMultiple
+}
+
+// hillRadius returns the Hill sphere radius, at periapsis, of the planet
+// or moon at the origin of ref frame rf.
+func hillRadius(rf *RefFrame) float64 {
+	p := S.PlanetInFrame(rf)
+	if p == nil || rf.Orbit == nil {
+		return math.Inf(1)
+	}
+	M := rf.Orbit.μ / GravitationalConstant
+	return rf.Orbit.SemimajorAxis() * (1 - rf.Orbit.e) * math.Cbrt(p.Mass/(3*M))
+}
+
+// gravityWell returns the body whose gravity well contains the entity, or
+// 0 if none.  The gravity well of the star of a star system is
+// hyperdriveStarWellRadii star radii, times hyperdriveWellMultiple.
+func gravityWell(e Id) Id {
+	rf := S.EntFrames[e]
+	system := rf.system()
+	for p := range S.Planets {
+		prf := S.EntFrames[p]
+		if prf == nil || prf.system() != system {
+			continue
+		}
+		if entityPosIn(e, prf, 0).Magnitude() < gravityWellRadius(prf) {
+			return p
+		}
+	}
+
+	if star := systemStar(rf); star != nil {
+		r := star.Body.Radius * hyperdriveStarWellRadii * hyperdriveWellMultiple
+		if entityPosIn(e, S.EntFrames[star.Entity], 0).Magnitude() < r {
+			return star.Entity
+		}
+	}
+	return 0
+}
+
+// cutShort cuts the route short at the boundary of the first star system it
+// crosses, other than the systems of origin and of the target star or
+// containing the start.  The jump then ends in interstellar space at the
+// boundary, with the star as its interdictor.
+func (hs *Hyperspace) cutShort(origin *Star) {
+	dir := hs.Heading
+	length := math.Inf(1)
+	if hs.Dest != nil {
+		dir = new(V3).Sub(hs.Dest, hs.Start)
+		length = dir.Magnitude()
+		if length == 0 {
+			return
+		}
+		dir.MulScalar(dir, 1/length)
+	}
+
+	var by *Star
+	for _, star := range S.StarsById {
+		if star == origin || star == hs.Target {
+			continue
+		}
+		c := new(V3).Sub(S.Pos[star.Entity], hs.Start)
+		r := S.EntFrames[star.Entity].Radius / (gridUnit * aum)
+		along := c.ScalarProduct(dir)
+		d2 := c.SquareMagnitude() - along*along
+		if c.Magnitude() < r || d2 >= r*r {
+			continue
+		}
+		if t := along - math.Sqrt(r*r-d2); t > 0 && t < length {
+			by, length = star, t
+		}
+	}
+	if by == nil {
+		return
+	}
+
+	// the speed is kept, arriving earlier at the boundary
+	travelSeconds := (length * gridUnit * aum) / (hs.Speed * speedOfLight)
+	hs.Dest = new(V3).Set(hs.Start).AddScaledVector(dir, length)
+	hs.Heading = nil
+	hs.Target = nil
+	hs.TargetTime = hs.Departure + travelSeconds
+	hs.Interdictor = by.Entity
+}
+
+// interdictor returns the active interdictor closest to the route segment
+// from a to b, in galactic grid units, within interdictionRange, and the
+// point of the segment closest to it.  It returns 0 if there is none.
+func interdictor(e Id, a, b *V3) (Id, *V3) {
+	r := interdictionRange / (gridUnit * aum)
+	var by Id
+	var at *V3
+	for i := range S.Interdictors {
+		if i == e || S.Hyperspace[i] != nil {
+			continue
+		}
+		c := galacticPos(i)
+		closest := closestPointOnSegment(a, b, c)
+		if d := new(V3).Sub(c, closest).Magnitude(); d < r {
+			by, at, r = i, closest, d
+		}
+	}
+	return by, at
+}
+
+// ActionInterdict enables or disables the interdiction field of the ship,
+// pulling hyperspace travelers passing within interdictionRange out of
+// hyperspace.
+type ActionInterdict struct {
+	entity Id
+	enable bool
+}
+
+func (a *ActionInterdict) Entity() Id { return a.entity }
+
+func (a *ActionInterdict) Execute() error {
+	if S.ShipClass[a.entity] == nil {
+		return fmt.Errorf("entity %v has no interdictor", a.entity)
+	}
+	if !a.enable {
+		delete(S.Interdictors, a.entity)
+		return nil
+	}
+	if S.Hyperspace[a.entity] != nil {
+		return fmt.Errorf("entity %v in hyperdrive", a.entity)
+	}
+	S.Interdictors[a.entity] = true
+	return nil
+}
@@ -0,0 +1,173 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGravityWell(t *testing.T) {
+	e, _ := devHyperdriveWorld()
+	home := S.EntFrames[e]
+	star := systemStar(home)
+	if well := gravityWell(e); well != 0 {
+		t.Errorf("ship at 5 AU within gravity well of %v", well)
+	}
+
+	// ships orbiting planets are within the planet's SOI
+	var planet Id
+	for p := range S.Planets {
+		planet = p
+	}
+	prf := S.EntFrames[planet]
+	moveEntity(e, prf)
+	S.Orb[e] = S.Planets[planet].DefaultOrbit()
+	S.SetIdle(e, prf, 0)
+	if well := gravityWell(e); well != planet {
+		t.Errorf("planet orbit within gravity well of %v, expected planet %v", well, planet)
+	}
+	if err := (&ActionHyperdrive{entity: e, heading: &V3{1, 0, 0}, speed: 1}).Execute(); err == nil {
+		t.Errorf("expected error for hyperdrive within gravity well")
+	}
+
+	// and ships close to the star within the star's
+	moveEntity(e, home)
+	μ := GravitationalConstant * star.Body.Mass * solarMass
+	r := 0.5 * star.Body.Radius * hyperdriveStarWellRadii
+	S.Orb[e] = StateVectorToOrbital(&V3{0, r, 0}, &V3{math.Sqrt(μ / r), 0, 0}, μ)
+	S.SetIdle(e, home, 0)
+	if well := gravityWell(e); well != star.Entity {
+		t.Errorf("star orbit within gravity well of %v, expected star %v", well, star.Entity)
+	}
+}
+
+func TestHillRadius(t *testing.T) {
+	ResetState()
+	μSun := GravitationalConstant * solarMass
+	rf := &RefFrame{
+		Parent: rootRF,
+		Orbit:  StateVectorToOrbital(&V3{aum, 0, 0}, &V3{0, math.Sqrt(μSun / aum), 0}, μSun),
+	}
+	S.AddPlanet(&Planet{Entity: S.NewEntity(), Mass: earthMass, Radius: earthRadius}, rf)
+
+	// Earth: Hill sphere of 1.5 million km and SOI of 925 thousand km
+	if r := hillRadius(rf); math.Abs(r-1.4966e9) > 1e6 {
+		t.Errorf("Hill radius %v", r)
+	}
+	if r := soiRadius(rf); math.Abs(r-9.25e8) > 1e6 {
+		t.Errorf("SOI radius %v", r)
+	}
+}
+
+func TestHyperdriveCutShort(t *testing.T) {
+	e, target := devHyperdriveWorld()
+	home := systemStar(S.EntFrames[e])
+	ge := &GameEngine{systems: []System{&Physics{}, &Hyperdrive{}}}
+	bus := S.MsgBus.Subscribe()
+
+	// a star system 30 AU off the route, halfway to the target
+	pos := new(V3).Add(S.Pos[home.Entity], &V3{0.3, 0, 1})
+	obstacle := NewStar(0.5)
+	obstacle.Entity = S.NewEntity()
+	GetSector(pos).addStarFixed(obstacle, pos)
+
+	jump := &ActionHyperdrive{entity: e, target: target.Entity, at: S.JumpDrive[e].ChargeTime(*S.Mass[e]) + 100}
+	if err := jump.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	hs := S.Hyperspace[e]
+	r := starSystemRadius / (gridUnit * aum)
+	if hs.Target != nil || hs.Interdictor != obstacle.Entity || hs.TargetTime >= jump.at {
+		t.Fatalf("route not cut short: %+v", hs)
+	}
+	if d := new(V3).Sub(hs.Dest, pos).Magnitude(); math.Abs(d-r) > 1e-9 {
+		t.Errorf("route cut short %v from the star system, expected %v", d, r)
+	}
+
+	for S.Hyperspace[e] != nil {
+		S.WorldTime += 1
+		ge.coastOrbits(1)
+		if err := ge.update(S.WorldTime, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rf := S.EntFrames[e]
+	if !S.Interstellar[rf] || new(V3).Sub(rf.Pos, hs.Dest).Magnitude() > 1e-9 {
+		t.Errorf("ship not dropped out at the star system boundary")
+	}
+	nextHyperdriveEvent(t, bus)
+	nextHyperdriveEvent(t, bus)
+	if ev := nextHyperdriveEvent(t, bus); ev.Event != EventHyperdriveInterdicted || ev.By != obstacle.Entity {
+		t.Errorf("unexpected event %+v", ev)
+	}
+}
+
+func TestInterdiction(t *testing.T) {
+	e, target := devHyperdriveWorld()
+	ge := &GameEngine{systems: []System{&Physics{}, &Hyperdrive{}}}
+	bus := S.MsgBus.Subscribe()
+
+	// an interdictor 5 AU off the route, halfway to the target
+	start := galacticPos(e)
+	mid := new(V3).Add(start, S.Pos[target.Entity])
+	mid.MulScalar(mid, 0.5)
+	at := new(V3).Add(mid, &V3{0, 0.05, 0})
+	i := DevNewShip()
+	rf, local := interstellarFrame(at)
+	moveEntity(i, rf)
+	S.Pos[i] = local
+	S.SetIdle(i, rf, 0)
+	if err := (&ActionInterdict{i, true}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	jump := &ActionHyperdrive{entity: e, target: target.Entity, at: S.JumpDrive[e].ChargeTime(*S.Mass[e]) + 100}
+	if err := jump.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	for S.Hyperspace[e] != nil {
+		S.WorldTime += 1
+		ge.coastOrbits(1)
+		if err := ge.update(S.WorldTime, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if S.WorldTime >= jump.at || !S.Interstellar[S.EntFrames[e]] {
+		t.Fatalf("ship not interdicted")
+	}
+	// pulled out on entering the interdiction range
+	r := interdictionRange / (gridUnit * aum)
+	if d := new(V3).Sub(galacticPos(e), at).Magnitude(); d < 0.05 || d > r {
+		t.Errorf("interdicted %v grid units from the interdictor", d)
+	}
+	nextHyperdriveEvent(t, bus)
+	nextHyperdriveEvent(t, bus)
+	if ev := nextHyperdriveEvent(t, bus); ev.Event != EventHyperdriveInterdicted || ev.By != i {
+		t.Errorf("unexpected event %+v", ev)
+	}
+
+	// disabled interdictors let ships pass
+	if err := (&ActionInterdict{i, false}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if by, _ := interdictor(e, start, S.Pos[target.Entity]); by != 0 {
+		t.Errorf("interdicted by disabled interdictor %v", by)
+	}
+}
@@ -0,0 +1,62 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+// Interstellar ref frames hold entities outside star systems, e.g. ships
+// dropping out of hyperspace between the stars.  They are created on
+// demand directly under the root frame, positioned in galactic grid units
+// like star system frames, and removed when the last entity leaves.
+
+// interstellarFrame returns the interstellar ref frame containing the
+// position pos, in galactic grid units, creating it if needed, and pos
+// relative the frame's origin in meters.
+func interstellarFrame(pos *V3) (*RefFrame, *V3) {
+	for rf := range S.Interstellar {
+		local := new(V3).Sub(pos, rf.Pos)
+		local.MulScalar(local, gridUnit*aum)
+		if local.Magnitude() < rf.Radius {
+			return rf, local
+		}
+	}
+
+	rf := &RefFrame{
+		Parent:      rootRF,
+		Pos:         new(V3).Set(pos),
+		Orbit:       nil,
+		Orientation: nil, // TODO
+		Radius:      interstellarFrameRadius,
+	}
+	S.Interstellar[rf] = true
+	return rf, new(V3)
+}
+
+// moveEntity moves the entity from its ref frame to ref frame rf, removing
+// the old frame if it is an interstellar frame left empty.  The caller
+// sets the entity's hot or idle state in rf.
+func moveEntity(e Id, rf *RefFrame) {
+	if old := S.EntFrames[e]; old != nil {
+		delete(S.HotEnts[old], e)
+		delete(S.IdleEnts[old], e)
+		if S.Interstellar[old] && len(S.HotEnts[old])+len(S.IdleEnts[old]) == 0 {
+			delete(S.Interstellar, old)
+			delete(S.HotEnts, old)
+			delete(S.IdleEnts, old)
+		}
+	}
+	S.EntFrames[e] = rf
+}
@@ -0,0 +1,241 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"fmt"
+	"math"
+)
+
+// Jump drive classes are analogous to engine classes (see propulsion.go):
+// each jump drive class is encoded as a set of constants and an empty
+// struct implements the JumpDriveClass interface.  A fitted jump drive
+// (JumpDrive) holds the dynamic drive state: its fuel and cooldown.
+//
+// Jumps through hyperspace are limited by the drive's max speed, charge
+// (spool-up) time and fuel, the latter two scaling linearly with the
+// ship's mass, and the drive cools down after each jump.  Fuel is burnt
+// by the distance travelled, when the ship leaves hyperspace.
+
+type JumpDriveClass interface {
+	// The mass in kilograms (kg) of the jump drive module, without fuel.
+	MassBase() float64
+
+	// Max speed as a multiple of the speed of light in vacuum.
+	MaxSpeedCap() float64
+
+	// Ship mass in kilograms (kg) the charge time and fuel consumption
+	// are rated for.
+	RatedMassBase() float64
+
+	// Time in seconds (s) to charge the drive before entering hyperspace.
+	ChargeTimeBase() float64
+
+	// Fuel consumption in kilograms (kg) per light year travelled.
+	FuelBase() float64
+
+	// Fuel tank capacity in kilograms (kg).
+	FuelCap() float64
+
+	// Time in seconds (s) after leaving hyperspace before the drive can
+	// charge again.
+	CooldownBase() float64
+}
+
+// JumpDrive is a jump drive module fitted to a ship.
+type JumpDrive struct {
+	Class JumpDriveClass
+
+	Fuel    float64 // kg
+	ReadyAt float64 // world time the drive has cooled down
+}
+
+// ChargeTime returns the charge time in seconds of the drive of a ship of
+// mass m.
+func (d *JumpDrive) ChargeTime(m float64) float64 {
+	return d.Class.ChargeTimeBase() * m / d.Class.RatedMassBase()
+}
+
+// FuelNeeded returns the fuel in kilograms a ship of mass m burns jumping
+// dist galactic grid units.
+func (d *JumpDrive) FuelNeeded(m, dist float64) float64 {
+	return d.Class.FuelBase() * (dist * gridUnit / auly) * m / d.Class.RatedMassBase()
+}
+
+// Range returns the distance in galactic grid units a ship of mass m can
+// jump with the fuel left, +Inf for drives that burn no fuel.
+func (d *JumpDrive) Range(m float64) float64 {
+	perUnit := d.FuelNeeded(m, 1)
+	if perUnit <= 0 {
+		return math.Inf(1)
+	}
+	return d.Fuel / perUnit
+}
+
+// FitJumpDrive fits a jump drive module of the class to the ship, with a
+// full fuel tank, replacing any fitted jump drive, and updates the ship's
+// mass.
+func FitJumpDrive(e Id, class JumpDriveClass) {
+	m := *S.Mass[e]
+	if old := S.JumpDrive[e]; old != nil {
+		m -= old.Class.MassBase() + old.Fuel
+	}
+	S.JumpDrive[e] = &JumpDrive{Class: class, Fuel: class.FuelCap()}
+	SetShipMass(e, m+class.MassBase()+class.FuelCap())
+}
+
+// JumpLimitError is returned for jumps exceeding the limits of the ship's
+// jump drive.  EarliestArrival is the earliest world time the ship can
+// arrive at the destination, or +Inf if the drive cannot reach it.
+type JumpLimitError struct {
+	Msg             string
+	EarliestArrival float64
+}
+
+func (err *JumpLimitError) Error() string {
+	if math.IsInf(err.EarliestArrival, 1) {
+		return err.Msg
+	}
+	return fmt.Sprintf("%s; earliest arrival %v", err.Msg, err.EarliestArrival)
+}
+
+// departure returns the earliest world time, from world time wTime, a ship
+// of mass m can leave for hyperspace: once the drive has cooled down and
+// charged.
+func (d *JumpDrive) departure(m, wTime float64) float64 {
+	return math.Max(wTime, d.ReadyAt) + d.ChargeTime(m)
+}
+
+// earliestArrival returns the earliest world time, from world time wTime,
+// a ship of mass m can arrive dist galactic grid units away.
+func (d *JumpDrive) earliestArrival(m, dist, wTime float64) float64 {
+	return d.departure(m, wTime) + (dist*gridUnit*aum)/(d.Class.MaxSpeedCap()*speedOfLight)
+}
+
+// checkArrival checks the arrival time tTime of the jump of a ship of mass
+// m from start to dest, both in galactic grid units, against the charge
+// time of its jump drive at world time wTime.
+func (d *JumpDrive) checkArrival(m float64, start, dest *V3, tTime, wTime float64) error {
+	if departure := d.departure(m, wTime); tTime <= departure {
+		dist := new(V3).Sub(dest, start).Magnitude()
+		msg := fmt.Sprintf("arrival %v before end of spool-up at %v", tTime, departure)
+		return &JumpLimitError{msg, d.earliestArrival(m, dist, wTime)}
+	}
+	return nil
+}
+
+// checkJump checks the jump of a ship of mass m against the limits of its
+// jump drive at world time wTime.  Jumps along a heading have no earliest
+// arrival and need fuel for some range.
+func (d *JumpDrive) checkJump(m float64, hs *Hyperspace, wTime float64) error {
+	maxSpeed := d.Class.MaxSpeedCap()
+	earliest := math.Inf(1)
+	if hs.Dest != nil {
+		dist := new(V3).Sub(hs.Dest, hs.Start).Magnitude()
+		if fuel := d.FuelNeeded(m, dist); fuel > d.Fuel {
+			return &JumpLimitError{fmt.Sprintf("jump needs %.1f kg fuel, %.1f kg left", fuel, d.Fuel), earliest}
+		}
+		earliest = d.earliestArrival(m, dist, wTime)
+	} else if d.Range(m) <= 0 {
+		return &JumpLimitError{"no fuel left for jump", earliest}
+	}
+
+	if wTime < d.ReadyAt {
+		return &JumpLimitError{fmt.Sprintf("drive cooling down until %v", d.ReadyAt), earliest}
+	}
+	if hs.Speed > maxSpeed {
+		return &JumpLimitError{fmt.Sprintf("speed %v above drive max %v", hs.Speed, maxSpeed), earliest}
+	}
+	return nil
+}
+
+// limitRange limits the jump of a ship of mass m along a heading to the
+// range of its jump drive.  Jumps of drives with unlimited range keep
+// their heading.
+func (d *JumpDrive) limitRange(m float64, hs *Hyperspace) {
+	r := d.Range(m)
+	if math.IsInf(r, 1) {
+		return
+	}
+	hs.Dest = new(V3).Set(hs.Start).AddScaledVector(hs.Heading, r)
+	hs.Heading = nil
+	hs.TargetTime = hs.Departure + (r*gridUnit*aum)/(hs.Speed*speedOfLight)
+}
+
+// endJump burns the fuel for the distance the ship travelled through
+// hyperspace and starts the drive cooldown.
+func endJump(e Id, wTime float64) {
+	d := S.JumpDrive[e]
+	hs := S.Hyperspace[e]
+	if d == nil || hs == nil {
+		return
+	}
+	m := *S.Mass[e]
+	fuel := math.Min(d.Fuel, d.FuelNeeded(m, new(V3).Sub(S.Pos[e], hs.Start).Magnitude()))
+	d.Fuel -= fuel
+	SetShipMass(e, m-fuel)
+	d.ReadyAt = wTime + d.Class.CooldownBase()
+}
+
+//
+// Jump Drive Classes
+//
+
+// Courier is a light jump drive with a fast charge and a small fuel tank;
+// suited for scouts and couriers.
+type Courier struct{}
+
+const (
+	massBaseCourier       = 3000      // kg
+	maxSpeedCapCourier    = 1000000.0 // multiple of c
+	ratedMassBaseCourier  = 50000     // kg
+	chargeTimeBaseCourier = 30.0      // s
+	fuelBaseCourier       = 10.0      // kg per light year
+	fuelCapCourier        = 500.0     // kg
+	cooldownBaseCourier   = 60.0      // s
+)
+
+func (c *Courier) MassBase() float64       { return massBaseCourier }
+func (c *Courier) MaxSpeedCap() float64    { return maxSpeedCapCourier }
+func (c *Courier) RatedMassBase() float64  { return ratedMassBaseCourier }
+func (c *Courier) ChargeTimeBase() float64 { return chargeTimeBaseCourier }
+func (c *Courier) FuelBase() float64       { return fuelBaseCourier }
+func (c *Courier) FuelCap() float64        { return fuelCapCourier }
+func (c *Courier) CooldownBase() float64   { return cooldownBaseCourier }
+
+// Freighter is a heavy jump drive rated for large ships, with a slow
+// charge and a large fuel tank; suited for haulers.
+type Freighter struct{}
+
+const (
+	massBaseFreighter       = 12000    // kg
+	maxSpeedCapFreighter    = 300000.0 // multiple of c
+	ratedMassBaseFreighter  = 400000   // kg
+	chargeTimeBaseFreighter = 120.0    // s
+	fuelBaseFreighter       = 40.0     // kg per light year
+	fuelCapFreighter        = 8000.0   // kg
+	cooldownBaseFreighter   = 300.0    // s
+)
+
+func (f *Freighter) MassBase() float64       { return massBaseFreighter }
+func (f *Freighter) MaxSpeedCap() float64    { return maxSpeedCapFreighter }
+func (f *Freighter) RatedMassBase() float64  { return ratedMassBaseFreighter }
+func (f *Freighter) ChargeTimeBase() float64 { return chargeTimeBaseFreighter }
+func (f *Freighter) FuelBase() float64       { return fuelBaseFreighter }
+func (f *Freighter) FuelCap() float64        { return fuelCapFreighter }
+func (f *Freighter) CooldownBase() float64   { return cooldownBaseFreighter }
@@ -0,0 +1,200 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func TestJumpDriveFit(t *testing.T) {
+	e := DevNewShip()
+	m := *S.Mass[e]
+	FitJumpDrive(e, &Freighter{})
+	exM := m - massBaseCourier - fuelCapCourier + massBaseFreighter + fuelCapFreighter
+	if d := S.JumpDrive[e]; d.Fuel != fuelCapFreighter || *S.Mass[e] != exM {
+		t.Errorf("got fuel %v mass %v, expected mass %v", d.Fuel, *S.Mass[e], exM)
+	}
+}
+
+func TestJumpDriveCharge(t *testing.T) {
+	e, star := devHyperdriveWorld()
+	d := S.JumpDrive[e]
+
+	// heavier ships charge longer
+	m := *S.Mass[e]
+	for _, mass := range []float64{m, 2 * m} {
+		SetShipMass(e, mass)
+		if err := (&ActionHyperdrive{entity: e, target: star.Entity, at: 1000}).Execute(); err != nil {
+			t.Fatal(err)
+		}
+		ex := chargeTimeBaseCourier * mass / ratedMassBaseCourier
+		if dep := S.Hyperspace[e].Departure; math.Abs(dep-ex) > 1e-9 || d.ChargeTime(mass) != dep {
+			t.Errorf("mass %v departs at %v, expected %v", mass, dep, ex)
+		}
+		delete(S.Hyperspace, e)
+	}
+}
+
+func TestJumpDriveSpeedLimit(t *testing.T) {
+	e, star := devHyperdriveWorld()
+	d := S.JumpDrive[e]
+	charge := d.ChargeTime(*S.Mass[e])
+
+	err := (&ActionHyperdrive{entity: e, target: star.Entity, at: charge + 1e-3}).Execute()
+	lerr, ok := err.(*JumpLimitError)
+	if !ok || S.Hyperspace[e] != nil {
+		t.Fatalf("expected jump limit error, got %v", err)
+	}
+	dist := new(V3).Sub(S.Pos[star.Entity], galacticPos(e)).Magnitude()
+	ex := charge + dist*gridUnit*aum/(maxSpeedCapCourier*speedOfLight)
+	if math.Abs(lerr.EarliestArrival-ex) > 1e-6 {
+		t.Errorf("earliest arrival %v, expected %v", lerr.EarliestArrival, ex)
+	}
+	// which is a valid arrival time
+	if err := (&ActionHyperdrive{entity: e, target: star.Entity, at: ex + 1e-6}).Execute(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestJumpDriveEarlyArrival(t *testing.T) {
+	e, star := devHyperdriveWorld()
+	d := S.JumpDrive[e]
+	charge := d.ChargeTime(*S.Mass[e])
+	dist := new(V3).Sub(S.Pos[star.Entity], galacticPos(e)).Magnitude()
+	transit := dist * gridUnit * aum / (maxSpeedCapCourier * speedOfLight)
+
+	// arrivals before the drive has charged, and cooled down
+	for _, readyAt := range []float64{0, 500} {
+		d.ReadyAt = readyAt
+		err := (&ActionHyperdrive{entity: e, target: star.Entity, at: charge / 2}).Execute()
+		lerr, ok := err.(*JumpLimitError)
+		if !ok || S.Hyperspace[e] != nil {
+			t.Fatalf("expected jump limit error, got %v", err)
+		}
+		ex := readyAt + charge + transit
+		if math.Abs(lerr.EarliestArrival-ex) > 1e-6 {
+			t.Errorf("ready at %v: earliest arrival %v, expected %v", readyAt, lerr.EarliestArrival, ex)
+		}
+	}
+}
+
+func TestJumpDriveFuel(t *testing.T) {
+	e, star := devHyperdriveWorld()
+	d := S.JumpDrive[e]
+	dist := new(V3).Sub(S.Pos[star.Entity], galacticPos(e)).Magnitude()
+	d.Fuel = d.FuelNeeded(*S.Mass[e], dist) / 2
+
+	err := (&ActionHyperdrive{entity: e, target: star.Entity, at: 1000}).Execute()
+	if lerr, ok := err.(*JumpLimitError); !ok || !math.IsInf(lerr.EarliestArrival, 1) {
+		t.Fatalf("expected out of fuel error, got %v", err)
+	}
+
+	// heading jumps stop when the fuel runs out
+	start := galacticPos(e)
+	if err := (&ActionHyperdrive{entity: e, heading: &V3{1, 0, 0}, speed: 1000}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	hs := S.Hyperspace[e]
+	ex := new(V3).Add(start, &V3{dist / 2, 0, 0})
+	if hs.Dest == nil || new(V3).Sub(hs.Dest, ex).Magnitude() > 1e-9 || math.IsInf(hs.TargetTime, 1) {
+		t.Errorf("heading jump to %v, expected %v", hs.Dest, ex.Fmt())
+	}
+}
+
+func TestJumpDriveEmptyTank(t *testing.T) {
+	e, _ := devHyperdriveWorld()
+	d := S.JumpDrive[e]
+	d.Fuel = 0
+
+	err := (&ActionHyperdrive{entity: e, heading: &V3{1, 0, 0}, speed: 1000}).Execute()
+	if _, ok := err.(*JumpLimitError); !ok || S.Hyperspace[e] != nil {
+		t.Fatalf("expected out of fuel error for heading jump, got %v", err)
+	}
+	if d.ReadyAt != 0 {
+		t.Errorf("rejected jump started cooldown until %v", d.ReadyAt)
+	}
+}
+
+// fuelFreeDrive is a Courier that burns no fuel.
+type fuelFreeDrive struct{ Courier }
+
+func (f *fuelFreeDrive) FuelBase() float64 { return 0 }
+
+func TestJumpDriveFuelFree(t *testing.T) {
+	e, _ := devHyperdriveWorld()
+	FitJumpDrive(e, &fuelFreeDrive{})
+	d := S.JumpDrive[e]
+	if r := d.Range(*S.Mass[e]); !math.IsInf(r, 1) {
+		t.Errorf("got range %v, expected +Inf", r)
+	}
+	d.Fuel = 0
+	if r := d.Range(*S.Mass[e]); !math.IsInf(r, 1) {
+		t.Errorf("got range %v with empty tank, expected +Inf", r)
+	}
+
+	// heading jumps are not limited
+	if err := (&ActionHyperdrive{entity: e, heading: &V3{1, 0, 0}, speed: 1000}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if hs := S.Hyperspace[e]; hs.Dest != nil || hs.Heading == nil {
+		t.Errorf("heading jump limited to %v", hs.Dest)
+	}
+}
+
+func TestJumpDriveBurnAndCooldown(t *testing.T) {
+	e, star := devHyperdriveWorld()
+	home := systemStar(S.EntFrames[e])
+	ge := &GameEngine{systems: []System{&Physics{}, &Hyperdrive{}}}
+	d := S.JumpDrive[e]
+	m := *S.Mass[e]
+	dist := new(V3).Sub(S.Pos[star.Entity], galacticPos(e)).Magnitude()
+	fuel := d.FuelNeeded(m, dist)
+
+	arrival := math.Ceil(d.ChargeTime(m)) + 100
+	if err := (&ActionHyperdrive{entity: e, target: star.Entity, at: arrival}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	for S.Hyperspace[e] != nil {
+		S.WorldTime += 1
+		ge.coastOrbits(1)
+		if err := ge.update(S.WorldTime, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if math.Abs(d.Fuel-(fuelCapCourier-fuel)) > 1e-9 || math.Abs(*S.Mass[e]-(m-fuel)) > 1e-9 {
+		t.Errorf("got fuel %v mass %v after burning %v kg", d.Fuel, *S.Mass[e], fuel)
+	}
+	if d.ReadyAt != arrival+cooldownBaseCourier {
+		t.Errorf("drive ready at %v", d.ReadyAt)
+	}
+
+	// the drive charges once cooled down
+	err := (&ActionHyperdrive{entity: e, target: home.Entity, at: S.WorldTime + 1000}).Execute()
+	lerr, ok := err.(*JumpLimitError)
+	if !ok {
+		t.Fatalf("expected cooldown error, got %v", err)
+	}
+	if lerr.EarliestArrival < d.ReadyAt+d.ChargeTime(*S.Mass[e]) {
+		t.Errorf("earliest arrival %v before drive charged", lerr.EarliestArrival)
+	}
+	err = (&ActionHyperdrive{entity: e, heading: &V3{1, 0, 0}, speed: 1}).Execute()
+	if _, ok := err.(*JumpLimitError); !ok {
+		t.Errorf("expected cooldown error for heading jump, got %v", err)
+	}
+}
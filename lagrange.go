@@ -0,0 +1,126 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"fmt"
+	"math"
+)
+
+// Lagrange points of a primary / secondary pair in the ref frame tree,
+// e.g. star / planet or planet / moon: the five points co-orbiting with
+// the secondary where the gravity of the two bodies balances.
+//
+// The points are computed for the circular restricted three-body problem,
+// and scaled and rotated with the secondary's actual position and velocity
+// for elliptic orbits.  L1, L2 and L3 are unstable; L4 and L5 are stable
+// for mass ratios below about 0.0385.
+//
+// https://en.wikipedia.org/wiki/Lagrange_point
+
+// LagrangePoint is the position and velocity of a Lagrange point relative
+// the primary.
+type LagrangePoint struct {
+	Pos *V3 `json:"pos"`
+	Vel *V3 `json:"vel"`
+}
+
+// LagrangePoints returns the Lagrange points L1 to L5 (at indices 0 to 4)
+// of the secondary on orbit o around the primary.  massRatio is the mass of
+// the secondary over the total mass of the pair.
+func (o *OE) LagrangePoints(massRatio float64) []*LagrangePoint {
+	μ := massRatio
+	pos, vel := o.OrbitalToStateVector()
+	normal := new(V3).VectorProduct(pos, vel)
+
+	// point returns the secondary's state scaled by k and rotated by φ
+	// around the orbit normal
+	point := func(k, φ float64) *LagrangePoint {
+		q := new(Q).SetAxisAngle(normal, φ)
+		p := q.Rotate(pos)
+		v := q.Rotate(vel)
+		return &LagrangePoint{p.MulScalar(p, k), v.MulScalar(v, k)}
+	}
+
+	// collinear points, in the rotating frame with the barycenter at the
+	// origin and unit distance between the bodies
+	hill := math.Cbrt(μ / 3)
+	l1 := collinearLagrangePoint(μ, 1-μ-hill)
+	l2 := collinearLagrangePoint(μ, 1-μ+hill)
+	l3 := collinearLagrangePoint(μ, -1-5*μ/12)
+
+	return []*LagrangePoint{
+		point(l1+μ, 0),
+		point(l2+μ, 0),
+		point(-(l3 + μ), math.Pi),
+		point(1, math.Pi/3),
+		point(1, -math.Pi/3),
+	}
+}
+
+// collinearLagrangePoint returns the position x of the collinear Lagrange
+// point closest to x0 in the rotating frame of the circular restricted
+// three-body problem, with the primary at -μ and the secondary at 1-μ.
+func collinearLagrangePoint(μ, x0 float64) float64 {
+	x := x0
+	for i := 0; i < lagrangeMaxIterations; i++ {
+		r1 := math.Abs(x + μ)
+		r2 := math.Abs(x - 1 + μ)
+		// sum of the centrifugal and gravitational accelerations
+		f := x - (1-μ)*(x+μ)/(r1*r1*r1) - μ*(x-1+μ)/(r2*r2*r2)
+		df := 1 + 2*(1-μ)/(r1*r1*r1) + 2*μ/(r2*r2*r2)
+		dx := f / df
+		x -= dx
+		if math.Abs(dx) < lagrangeTolerance {
+			break
+		}
+	}
+	return x
+}
+
+// FrameLagrangePoints returns the Lagrange points of the planet at the
+// origin of ref frame rf and the primary at the origin of its parent frame,
+// relative the primary.  See LagrangePoints.
+func FrameLagrangePoints(rf *RefFrame) ([]*LagrangePoint, error) {
+	planet := S.PlanetInFrame(rf)
+	if planet == nil || rf.Orbit == nil || rf.Parent == nil {
+		return nil, fmt.Errorf("ref frame does not hold an orbiting planet")
+	}
+	primaryMass := rf.Orbit.μ / GravitationalConstant
+	return rf.Orbit.LagrangePoints(planet.Mass / (primaryMass + planet.Mass)), nil
+}
+
+// PlaceAtLagrangePoint places the entity at the L4 or L5 Lagrange point
+// (n of 4 or 5) of the planet at the origin of ref frame rf, co-orbiting
+// the primary with the planet.  Entities orbit the primary only, so the
+// collinear points, where the planet's gravity is needed to keep the
+// entity in place, are not supported.
+func PlaceAtLagrangePoint(e Id, rf *RefFrame, n int) error {
+	if n != 4 && n != 5 {
+		return fmt.Errorf("entities can only be placed at L4 or L5, not L%v", n)
+	}
+	points, err := FrameLagrangePoints(rf)
+	if err != nil {
+		return err
+	}
+	p := points[n-1]
+	moveEntity(e, rf.Parent)
+	S.Orb[e] = StateVectorToOrbital(p.Pos, p.Vel, rf.Orbit.μ)
+	S.SetIdle(e, rf.Parent, S.WorldTime)
+	return nil
+}
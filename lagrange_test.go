@@ -0,0 +1,159 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLagrangePointsEarthMoon(t *testing.T) {
+	μ := 0.012150585
+	o := StateVectorToOrbital(&V3{1, 0, 0}, &V3{0, 1, 0}, 1)
+	points := o.LagrangePoints(μ)
+
+	// barycentric x of the collinear points
+	for i, x := range []float64{0.836915, 1.155682, -1.005063} {
+		if got := points[i].Pos.X - μ; math.Abs(got-x) > 1e-6 || math.Abs(points[i].Pos.Y) > 1e-12 {
+			t.Errorf("L%v at %v, expected %v", i+1, points[i].Pos.Fmt(), x-(-μ))
+		}
+	}
+	// triangular points form equilateral triangles with the bodies
+	for i, y := range []float64{math.Sqrt(3) / 2, -math.Sqrt(3) / 2} {
+		p := points[3+i].Pos
+		if math.Abs(p.X-0.5) > 1e-12 || math.Abs(p.Y-y) > 1e-12 {
+			t.Errorf("L%v at %v", 4+i, p.Fmt())
+		}
+	}
+}
+
+// propagateThreeBody integrates the motion of a massless body with state
+// pos, vel relative the primary of the pair of the secondary on the
+// circular orbit o, with mass ratio μ, for dt seconds.  It returns the
+// final state relative the primary.
+func propagateThreeBody(o *OE, μ float64, pos, vel *V3, dt float64, steps int) (*V3, *V3) {
+	// barycentric inertial frame: the bodies circle the barycenter
+	gm := o.μ
+	bodies := func(t float64) (*V3, *V3) {
+		r, _ := o.AtTime(t).OrbitalToStateVector()
+		primary := new(V3).MulScalar(r, -μ)
+		return primary, new(V3).Add(primary, r)
+	}
+	acc := func(t float64, p *V3) *V3 {
+		p1, p2 := bodies(t)
+		a := new(V3)
+		for _, b := range []struct {
+			pos *V3
+			gm  float64
+		}{{p1, (1 - μ) * gm}, {p2, μ * gm}} {
+			d := new(V3).Sub(b.pos, p)
+			m := d.Magnitude()
+			a.AddScaledVector(d, b.gm/(m*m*m))
+		}
+		return a
+	}
+
+	p1, _ := bodies(0)
+	_, v1 := o.OrbitalToStateVector()
+	v1.MulScalar(v1, -μ)
+	p := new(V3).Add(pos, p1)
+	v := new(V3).Add(vel, v1)
+
+	// RK4
+	h := dt / float64(steps)
+	for i := 0; i < steps; i++ {
+		t := float64(i) * h
+		k1v := acc(t, p)
+		k1p := new(V3).Set(v)
+		k2v := acc(t+h/2, new(V3).Set(p).AddScaledVector(k1p, h/2))
+		k2p := new(V3).Set(v).AddScaledVector(k1v, h/2)
+		k3v := acc(t+h/2, new(V3).Set(p).AddScaledVector(k2p, h/2))
+		k3p := new(V3).Set(v).AddScaledVector(k2v, h/2)
+		k4v := acc(t+h, new(V3).Set(p).AddScaledVector(k3p, h))
+		k4p := new(V3).Set(v).AddScaledVector(k3v, h)
+
+		p.AddScaledVector(k1p, h/6).AddScaledVector(k2p, h/3).AddScaledVector(k3p, h/3).AddScaledVector(k4p, h/6)
+		v.AddScaledVector(k1v, h/6).AddScaledVector(k2v, h/3).AddScaledVector(k3v, h/3).AddScaledVector(k4v, h/6)
+	}
+
+	p1, _ = bodies(dt)
+	_, v1 = o.AtTime(dt).OrbitalToStateVector()
+	v1.MulScalar(v1, -μ)
+	return p.Sub(p, p1), v.Sub(v, v1)
+}
+
+func TestLagrangePointsStability(t *testing.T) {
+	// sun / jupiter like pair, in units of the distance between the bodies
+	μ := 0.000954
+	o := StateVectorToOrbital(&V3{1, 0, 0}, &V3{0, 1, 0}, 1)
+	period := o.Period()
+	points := o.LagrangePoints(μ)
+
+	// all points are equilibria of the rotating frame: a body placed at a
+	// point stays there, until the instability of L1 to L3 sets in
+	for i, p := range points {
+		pos, _ := propagateThreeBody(o, μ, p.Pos, p.Vel, period/10, 1000)
+		ex := o.AtTime(period / 10).LagrangePoints(μ)[i].Pos
+		if d := new(V3).Sub(pos, ex).Magnitude(); d > 1e-6 {
+			t.Errorf("L%v: drifted %v in 0.1 periods", i+1, d)
+		}
+	}
+
+	// L4 and L5 are stable over many periods
+	for i, p := range points[3:] {
+		pos, _ := propagateThreeBody(o, μ, p.Pos, p.Vel, 50*period, 50*500)
+		ex := o.AtTime(50 * period).LagrangePoints(μ)[3+i].Pos
+		if d := new(V3).Sub(pos, ex).Magnitude(); d > 1e-3 {
+			t.Errorf("L%v: drifted %v in 50 periods", i+4, d)
+		}
+	}
+}
+
+func TestPlaceAtLagrangePoint(t *testing.T) {
+	e := devOrbitingShip()
+	rf := S.EntFrames[e]
+	starRF := &RefFrame{Parent: rootRF}
+	μSun := GravitationalConstant * solarMass
+	rf.Parent = starRF
+	rf.Orbit = StateVectorToOrbital(&V3{aum, 0, 0}, &V3{0, math.Sqrt(μSun / aum), 0}, μSun)
+
+	if err := PlaceAtLagrangePoint(e, rf, 1); err == nil {
+		t.Errorf("expected error for L1")
+	}
+	if err := PlaceAtLagrangePoint(e, rf, 4); err != nil {
+		t.Fatal(err)
+	}
+	if S.EntFrames[e] != starRF || !S.IdleEnts[starRF][e] || S.IdleEnts[rf][e] {
+		t.Errorf("entity not moved to the primary's frame")
+	}
+
+	// the entity co-orbits 60 degrees ahead of the planet
+	o := S.Orb[e]
+	if math.Abs(o.Period()-rf.Orbit.Period()) > 1e-3 {
+		t.Errorf("period %v, expected %v", o.Period(), rf.Orbit.Period())
+	}
+	for _, dt := range []float64{0, o.Period() / 3} {
+		pos, _ := o.AtTime(dt).OrbitalToStateVector()
+		planet, _ := rf.Orbit.AtTime(dt).OrbitalToStateVector()
+		pos.Normalise()
+		planet.Normalise()
+		if angle := math.Acos(pos.ScalarProduct(planet)); math.Abs(angle-math.Pi/3) > 1e-6 {
+			t.Errorf("at %v: %v degrees from the planet", dt, RadToDeg(angle))
+		}
+	}
+}
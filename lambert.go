@@ -0,0 +1,234 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Lambert's problem and transfer planning between two orbits around the
+// same primary.
+//
+// NOTE: referenced equations, algorithms and examples are from reference
+//       [1] of orbit.go (Curtis).
+
+// Lambert returns the velocities at r1 and r2 of the orbit around a primary
+// with gravitational parameter μ that takes the orbiter from r1 to r2 in
+// time of flight dt seconds.  If prograde is true the transfer moves in the
+// direction of positive Z angular momentum, otherwise in the opposite.
+// See Algorithm 5.2.
+func Lambert(r1, r2 *V3, dt, μ float64, prograde bool) (*V3, *V3, error) {
+	if dt <= 0 {
+		return nil, nil, fmt.Errorf("non-positive time of flight %v", dt)
+	}
+	r1Mag, r2Mag := r1.Magnitude(), r2.Magnitude()
+
+	// Eqn 5.26: transfer angle
+	cross := new(V3).VectorProduct(r1, r2)
+	Δθ := math.Acos(math.Max(-1, math.Min(1, r1.ScalarProduct(r2)/(r1Mag*r2Mag))))
+	if (prograde && cross.Z < 0) || (!prograde && cross.Z >= 0) {
+		Δθ = twoPi - Δθ
+	}
+	if math.Abs(math.Sin(Δθ)) < lambertTolerance {
+		return nil, nil, errors.New("transfer angle of 0 or 180 degrees: transfer plane undefined")
+	}
+
+	// Eqn 5.35
+	A := math.Sin(Δθ) * math.Sqrt(r1Mag*r2Mag/(1-math.Cos(Δθ)))
+
+	// Eqn 5.38
+	y := func(z float64) float64 {
+		return r1Mag + r2Mag + A*(z*stumpffS(z)-1)/math.Sqrt(stumpffC(z))
+	}
+	// Eqn 5.40
+	F := func(z float64) float64 {
+		yz := y(z)
+		return math.Pow(yz/stumpffC(z), 1.5)*stumpffS(z) + A*math.Sqrt(yz) - math.Sqrt(μ)*dt
+	}
+	// Eqn 5.43
+	dFdz := func(z float64) float64 {
+		yz := y(z)
+		if z == 0 {
+			return math.Sqrt2/40*math.Pow(yz, 1.5) + A/8*(math.Sqrt(yz)+A*math.Sqrt(1/(2*yz)))
+		}
+		C, S := stumpffC(z), stumpffS(z)
+		return math.Pow(yz/C, 1.5)*(1/(2*z)*(C-3*S/(2*C))+3*S*S/(4*C)) +
+			A/8*(3*S/C*math.Sqrt(yz)+A*math.Sqrt(C/yz))
+	}
+
+	// starting value: step z until F changes sign, skipping z where y is
+	// negative (Algorithm 5.2, step 4)
+	z := -100.0
+	for math.IsNaN(F(z)) || F(z) < 0 {
+		z += 0.1
+		if z > 4*math.Pi*math.Pi {
+			return nil, nil, errors.New("no Lambert solution found")
+		}
+	}
+
+	// Newton's method
+	for n := 0; ; n++ {
+		ratio := F(z) / dFdz(z)
+		z -= ratio
+		if math.Abs(ratio) <= lambertTolerance {
+			break
+		}
+		if n == lambertMaxIterations || math.IsNaN(z) {
+			return nil, nil, errors.New("Lambert solution did not converge")
+		}
+	}
+
+	// Eqn 5.46: Lagrange coefficients
+	yz := y(z)
+	f := 1 - yz/r1Mag
+	g := A * math.Sqrt(yz/μ)
+	gdot := 1 - yz/r2Mag
+
+	// Eqn 5.28 and 5.29
+	v1 := new(V3).AddScaledVector(r2, 1/g)
+	v1.AddScaledVector(r1, -f/g)
+	v2 := new(V3).AddScaledVector(r2, gdot/g)
+	v2.AddScaledVector(r1, -1/g)
+	return v1, v2, nil
+}
+
+// stumpffS returns the Stumpff function S(z).  See Eqn 3.52.
+func stumpffS(z float64) float64 {
+	switch {
+	case z > 0:
+		sz := math.Sqrt(z)
+		return (sz - math.Sin(sz)) / (sz * sz * sz)
+	case z < 0:
+		sz := math.Sqrt(-z)
+		return (math.Sinh(sz) - sz) / (sz * sz * sz)
+	}
+	return 1.0 / 6.0
+}
+
+// stumpffC returns the Stumpff function C(z).  See Eqn 3.53.
+func stumpffC(z float64) float64 {
+	switch {
+	case z > 0:
+		return (1 - math.Cos(math.Sqrt(z))) / z
+	case z < 0:
+		return (math.Cosh(math.Sqrt(-z)) - 1) / -z
+	}
+	return 0.5
+}
+
+// Transfer is a transfer from an origin orbit to a rendezvous with a
+// target orbiter, found by Porkchop.
+type Transfer struct {
+	// Departure and arrival world times
+	Depart float64 `json:"depart"`
+	Arrive float64 `json:"arrive"`
+
+	// Δv at departure and arrival in ref frame coordinates, and the sum
+	// of their magnitudes
+	DepartDeltaV *V3     `json:"departDeltaV"`
+	ArriveDeltaV *V3     `json:"arriveDeltaV"`
+	DeltaV       float64 `json:"deltaV"`
+
+	// Maneuver nodes executing the transfer
+	DepartNode *ManeuverNode `json:"departNode"`
+	ArriveNode *ManeuverNode `json:"arriveNode"`
+}
+
+// PorkchopGrid holds the search space of Porkchop.  Departure times are
+// relative the time of the orbits' current positions.
+type PorkchopGrid struct {
+	DepartMin, DepartMax, DepartStep float64
+	TOFMin, TOFMax, TOFStep          float64
+
+	// Retrograde searches for transfers against the orbits' direction
+	Retrograde bool
+}
+
+// Porkchop evaluates transfers from origin to target over the grid of
+// departure times and times of flight, like a porkchop plot [1].  It
+// returns all transfers found and the minimum Δv one.  Both orbits must be
+// around the same primary.  worldTime is the world time of the orbits'
+// current positions, used for the transfer times.
+//
+// [1] https://en.wikipedia.org/wiki/Porkchop_plot
+func Porkchop(origin, target *OE, grid *PorkchopGrid, worldTime float64) ([]*Transfer, *Transfer, error) {
+	for _, x := range []float64{grid.DepartMin, grid.DepartMax, grid.DepartStep, grid.TOFMin, grid.TOFMax, grid.TOFStep} {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return nil, nil, errors.New("invalid porkchop grid")
+		}
+	}
+	if grid.DepartStep <= 0 || grid.TOFStep <= 0 || grid.TOFMin <= 0 ||
+		grid.DepartMax < grid.DepartMin || grid.TOFMax < grid.TOFMin {
+		return nil, nil, errors.New("invalid porkchop grid")
+	}
+	departs := math.Floor((grid.DepartMax-grid.DepartMin)/grid.DepartStep) + 1
+	tofs := math.Floor((grid.TOFMax-grid.TOFMin)/grid.TOFStep) + 1
+	if n := departs * tofs; n > porkchopMaxPoints {
+		return nil, nil, fmt.Errorf("porkchop grid of %.0f points larger than %v", n, porkchopMaxPoints)
+	}
+
+	transfers := []*Transfer{}
+	var best *Transfer
+	for i := 0.0; i < departs; i++ {
+		depart := grid.DepartMin + i*grid.DepartStep
+		o1 := origin.AtTime(depart)
+		r1, v1 := o1.OrbitalToStateVector()
+		for j := 0.0; j < tofs; j++ {
+			tof := grid.TOFMin + j*grid.TOFStep
+			r2, v2 := target.AtTime(depart + tof).OrbitalToStateVector()
+			lv1, lv2, err := Lambert(r1, r2, tof, origin.μ, !grid.Retrograde)
+			if err != nil {
+				continue
+			}
+
+			t := &Transfer{
+				Depart:       worldTime + depart,
+				Arrive:       worldTime + depart + tof,
+				DepartDeltaV: new(V3).Sub(lv1, v1),
+				ArriveDeltaV: new(V3).Sub(v2, lv2),
+			}
+			t.DeltaV = t.DepartDeltaV.Magnitude() + t.ArriveDeltaV.Magnitude()
+			t.DepartNode = newManeuverNode(o1, t.Depart, t.DepartDeltaV)
+			t.ArriveNode = newManeuverNode(StateVectorToOrbital(r2, lv2, origin.μ), t.Arrive, t.ArriveDeltaV)
+
+			transfers = append(transfers, t)
+			if best == nil || t.DeltaV < best.DeltaV {
+				best = t
+			}
+		}
+	}
+	if best == nil {
+		return nil, nil, errors.New("no transfer found")
+	}
+	return transfers, best, nil
+}
+
+// EntityPorkchop runs Porkchop for a transfer of entity e to a rendezvous
+// with entity target, with departure times relative the current world
+// time.  Both entities must orbit the same primary.
+func EntityPorkchop(e, target Id, grid *PorkchopGrid) ([]*Transfer, *Transfer, error) {
+	if S.Orb[e] == nil || S.Orb[target] == nil {
+		return nil, nil, errors.New("both entities must be in orbit")
+	}
+	if S.EntFrames[e] != S.EntFrames[target] {
+		return nil, nil, errors.New("entities orbit different primaries")
+	}
+	return Porkchop(S.Orb[e], S.Orb[target], grid, S.WorldTime)
+}
@@ -0,0 +1,119 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLambert(t *testing.T) {
+	// This test uses values in Example 5.2.
+	r1 := &V3{5000, 10000, 2100}
+	r2 := &V3{-14600, 2500, 7000}
+	v1, v2, err := Lambert(r1, r2, 3600, 398600, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exV1 := &V3{-5.9925, 1.9254, 3.2456}
+	exV2 := &V3{-3.3125, -4.1966, -0.38529}
+	if d := new(V3).Sub(v1, exV1).Magnitude(); d > 1e-4 {
+		t.Errorf("v1: got %v, expected %v", v1.Fmt(), exV1.Fmt())
+	}
+	if d := new(V3).Sub(v2, exV2).Magnitude(); d > 1e-4 {
+		t.Errorf("v2: got %v, expected %v", v2.Fmt(), exV2.Fmt())
+	}
+
+	// the transfer orbit reaches r2 after the time of flight
+	o := StateVectorToOrbital(r1, v1, 398600)
+	if pos, _ := o.AtTime(3600).OrbitalToStateVector(); new(V3).Sub(pos, r2).Magnitude() > 1e-3 {
+		t.Errorf("transfer ends at %v, expected %v", pos.Fmt(), r2.Fmt())
+	}
+
+	if _, _, err := Lambert(r1, new(V3).MulScalar(r1, 2), 3600, 398600, true); err == nil {
+		t.Errorf("expected error for collinear positions")
+	}
+}
+
+func TestPorkchop(t *testing.T) {
+	μ := 398600.0
+	circular := func(r, θ float64) *OE {
+		v := math.Sqrt(μ / r)
+		o := StateVectorToOrbital(&V3{r, 0, 0}, &V3{0, v, 0}, μ)
+		return o.AtTime(θ / twoPi * o.Period())
+	}
+	origin := circular(7000, 0)
+	target := circular(12000, 1.0)
+
+	grid := &PorkchopGrid{
+		DepartMin: 0, DepartMax: 11000, DepartStep: 120,
+		TOFMin: 3000, TOFMax: 6000, TOFStep: 120,
+	}
+	transfers, best, err := Porkchop(origin, target, grid, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transfers) < 2000 {
+		t.Errorf("only %d transfers found", len(transfers))
+	}
+
+	// coplanar circular orbits: no transfer beats Hohmann, and the best
+	// of the search is close to it
+	hohmann := 1.7490
+	if best.DeltaV < hohmann-1e-3 || best.DeltaV > hohmann*1.03 {
+		t.Errorf("best Δv %v, Hohmann %v", best.DeltaV, hohmann)
+	}
+
+	// flying the departure node ends at the target
+	depart := origin.AtTime(best.Depart - 1000)
+	dv := depart.ManeuverDeltaV(&V3{best.DepartNode.Prograde, best.DepartNode.Normal, best.DepartNode.Radial})
+	if d := new(V3).Sub(dv, best.DepartDeltaV).Magnitude(); d > 1e-9 {
+		t.Errorf("departure node Δv off by %v", d)
+	}
+	arrival, _ := depart.AfterImpulse(dv).AtTime(best.Arrive - best.Depart).OrbitalToStateVector()
+	tpos, _ := target.AtTime(best.Arrive - 1000).OrbitalToStateVector()
+	if d := new(V3).Sub(arrival, tpos).Magnitude(); d > 1 {
+		t.Errorf("transfer misses target by %v km", d)
+	}
+}
+
+func TestPorkchopInvalidGrid(t *testing.T) {
+	μ := 398600.0
+	origin := StateVectorToOrbital(&V3{7000, 0, 0}, &V3{0, math.Sqrt(μ / 7000), 0}, μ)
+	target := StateVectorToOrbital(&V3{12000, 0, 0}, &V3{0, math.Sqrt(μ / 12000), 0}, μ)
+
+	for _, grid := range []*PorkchopGrid{
+		{DepartMin: 0, DepartMax: 0, DepartStep: 1, TOFMin: 1, TOFMax: math.Inf(1), TOFStep: 1},
+		{DepartMin: 0, DepartMax: math.NaN(), DepartStep: 1, TOFMin: 1, TOFMax: 10, TOFStep: 1},
+		{DepartMin: 10, DepartMax: 0, DepartStep: -1, TOFMin: 1, TOFMax: 10, TOFStep: 1},
+		{DepartMin: 10, DepartMax: 0, DepartStep: 1, TOFMin: 10, TOFMax: 1, TOFStep: -1},
+		{DepartMin: 0, DepartMax: 10, DepartStep: 1, TOFMin: 1, TOFMax: 10, TOFStep: math.NaN()},
+		{DepartMin: 0, DepartMax: 1000, DepartStep: 1, TOFMin: 1, TOFMax: 1000, TOFStep: 1},
+	} {
+		if _, _, err := Porkchop(origin, target, grid, 0); err == nil {
+			t.Errorf("expected error for grid %+v", grid)
+		}
+	}
+
+	// a single point grid
+	grid := &PorkchopGrid{DepartMin: 0, DepartMax: 0, DepartStep: 1, TOFMin: 3000, TOFMax: 3000, TOFStep: 1}
+	if transfers, _, err := Porkchop(origin, target, grid, 0); err != nil || len(transfers) != 1 {
+		t.Errorf("got %v transfers, %v for single point grid", len(transfers), err)
+	}
+}
@@ -0,0 +1,362 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Maneuver nodes: a velocity change (Δv) planned for a world time.
+//
+// Players plan maneuvers in Δv relative to their orbit at the time of the
+// burn: prograde along the velocity, normal along the orbital angular
+// momentum and radial out, completing a right-handed frame.
+//
+// A maneuver is executed as a finite burn centered on the maneuver time:
+// a timer fires ahead of the burn, the attitude autopilot points the ship
+// along the Δv and the engine burns at full thrust until the achieved Δv
+// matches the planned one.  The predicted orbit after the burn assumes an
+// impulsive burn, so it differs slightly from the achieved orbit for long
+// burns.
+
+// EventBurnFailed is posted when a burn is given up, the ship not aligned
+// for it in time.
+const EventBurnFailed = "burn failed"
+
+// BurnEvent is posted on the message bus for burn events of an entity.
+type BurnEvent struct {
+	Entity Id      `json:"entity"`
+	Event  string  `json:"event"`
+	Time   float64 `json:"time"` // world time
+}
+
+// ManeuverPlan is the plan of a maneuver, returned to the client when the
+// maneuver is scheduled.
+type ManeuverPlan struct {
+	Timer Id `json:"timer"`
+
+	// Burn start world time and estimated duration in seconds
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+
+	// Δv in ref frame coordinates
+	DeltaV *V3 `json:"deltaV"`
+
+	// Predicted orbit after the burn
+	Orbit *OE `json:"orbit"`
+}
+
+// ManeuverNode is a maneuver that is ready to execute: its JSON encoding
+// is the params of the maneuver action.
+type ManeuverNode struct {
+	At       float64 `json:"at"` // world time
+	Prograde float64 `json:"prograde"`
+	Normal   float64 `json:"normal"`
+	Radial   float64 `json:"radial"`
+}
+
+// newManeuverNode returns the node for Δv dv in ref frame coordinates at
+// world time at, on orbit o at the time of the burn.
+func newManeuverNode(o *OE, at float64, dv *V3) *ManeuverNode {
+	c := o.ManeuverComponents(dv)
+	return &ManeuverNode{at, c.X, c.Y, c.Z}
+}
+
+// DeltaV returns the magnitude of the node's Δv.
+func (n *ManeuverNode) DeltaV() float64 {
+	return (&V3{n.Prograde, n.Normal, n.Radial}).Magnitude()
+}
+
+// Action returns the maneuver action executing the node for entity e.
+func (n *ManeuverNode) Action(e Id) Action {
+	return &ActionManeuver{entity: e, at: n.At, dv: &V3{n.Prograde, n.Normal, n.Radial}}
+}
+
+// ManeuverDeltaV returns the Δv with prograde, normal and radial out
+// components (X, Y and Z of dv) in the ref frame coordinates of the orbit.
+func (o *OE) ManeuverDeltaV(dv *V3) *V3 {
+	prograde, normal, radial := o.maneuverFrame()
+	world := new(V3).MulScalar(prograde, dv.X)
+	world.AddScaledVector(normal, dv.Y)
+	return world.AddScaledVector(radial, dv.Z)
+}
+
+// ManeuverComponents is the inverse of ManeuverDeltaV: it returns the
+// prograde, normal and radial out components of dv in ref frame
+// coordinates.
+func (o *OE) ManeuverComponents(dv *V3) *V3 {
+	prograde, normal, radial := o.maneuverFrame()
+	return &V3{dv.ScalarProduct(prograde), dv.ScalarProduct(normal), dv.ScalarProduct(radial)}
+}
+
+// maneuverFrame returns the prograde, normal and radial out unit vectors
+// at the orbiter's current position.
+func (o *OE) maneuverFrame() (*V3, *V3, *V3) {
+	pos, vel := o.OrbitalToStateVector()
+	prograde := new(V3).Set(vel)
+	prograde.Normalise()
+	normal := new(V3).VectorProduct(pos, vel)
+	normal.Normalise()
+	radial := new(V3).VectorProduct(prograde, normal)
+	return prograde, normal, radial
+}
+
+// AfterImpulse returns the orbit after an instantaneous velocity change
+// dv in ref frame coordinates at the orbiter's current position.
+func (o *OE) AfterImpulse(dv *V3) *OE {
+	pos, vel := o.OrbitalToStateVector()
+	return StateVectorToOrbital(pos, vel.Add(vel, dv), o.μ)
+}
+
+// burnDuration estimates the time for the ship's engine to deliver Δv of
+// magnitude dv at full thrust, including spooling up and down.
+func burnDuration(e Id, dv float64) (float64, error) {
+	engine, ok := S.Engine[e].(*MainEngine)
+	if !ok {
+		return 0, fmt.Errorf("entity %v has no engine", e)
+	}
+	return dv**S.Mass[e]/engine.MaxThrust() + engine.Class.SpoolTimeBase(), nil
+}
+
+// engineInUse returns whether a force generator is driving the engine.
+func engineInUse(e Id) bool {
+	for _, fg := range S.ForceGens[e] {
+		switch fg.(type) {
+		case *EngineForceGen, *BurnForceGen:
+			if !fg.IsExpired() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ActionManeuver schedules a maneuver at world time at.  The Δv (dv) is
+// in prograde, normal and radial out components.
+type ActionManeuver struct {
+	entity Id
+	at     float64
+	dv     *V3
+
+	origin *ActionRequest
+	plan   *ManeuverPlan
+}
+
+func (a *ActionManeuver) Entity() Id                   { return a.entity }
+func (a *ActionManeuver) setOrigin(req *ActionRequest) { a.origin = req }
+func (a *ActionManeuver) result() interface{}          { return a.plan }
+
+func (a *ActionManeuver) Execute() error {
+	o := S.Orb[a.entity]
+	if o == nil {
+		return fmt.Errorf("entity %v is not in orbit", a.entity)
+	}
+
+	duration, err := burnDuration(a.entity, a.dv.Magnitude())
+	if err != nil {
+		return err
+	}
+	start := a.at - duration/2
+	if start-maneuverAlignTime < S.WorldTime {
+		return fmt.Errorf("maneuver at %v too soon, burn of %.1f s must start after %.1f",
+			a.at, duration, S.WorldTime+maneuverAlignTime)
+	}
+
+	atBurn := o.AtTime(a.at - S.WorldTime)
+	dv := atBurn.ManeuverDeltaV(a.dv)
+
+	params, err := json.Marshal(burnParams{dv, &start})
+	if err != nil {
+		return err
+	}
+	burn := &ActionBurn{a.entity, dv, start}
+	sched := &ActionSchedule{
+		entity: a.entity,
+		at:     start - maneuverAlignTime,
+		env: &ActionEnvelope{
+			Version: actionVersion,
+			Action:  "burn",
+			Entity:  strconv.FormatUint(uint64(a.entity), 10),
+			Params:  params,
+		},
+		action: burn,
+		origin: a.origin,
+	}
+	if err := sched.Execute(); err != nil {
+		return err
+	}
+
+	a.plan = &ManeuverPlan{
+		Timer:    sched.timer,
+		Start:    start,
+		Duration: duration,
+		DeltaV:   dv,
+		Orbit:    atBurn.AfterImpulse(dv),
+	}
+	return nil
+}
+
+type burnParams struct {
+	DeltaV *V3      `json:"dv"`
+	Start  *float64 `json:"start,omitempty"`
+}
+
+// ActionBurn burns the ship's engine until the velocity change dv, in ref
+// frame coordinates, is achieved.  The burn starts once the ship is
+// aligned with dv and the world time start is reached, and fails if the
+// ship is not aligned burnAlignTimeout seconds after start.
+type ActionBurn struct {
+	entity Id
+	dv     *V3
+	start  float64
+}
+
+func (a *ActionBurn) Entity() Id { return a.entity }
+
+func (a *ActionBurn) Execute() error {
+	if S.Engine[a.entity] == nil {
+		return fmt.Errorf("entity %v has no engine", a.entity)
+	}
+	if S.ShipClass[a.entity] == nil || S.Rot[a.entity] == nil {
+		return fmt.Errorf("entity %v cannot rotate", a.entity)
+	}
+	if engineInUse(a.entity) {
+		return fmt.Errorf("engine of entity %v in use", a.entity)
+	}
+
+	cancelAttitude(a.entity)
+	attitude := &AttitudeForceGen{Mode: AttitudeDirection, Dir: new(V3).Set(a.dv)}
+	S.AddForceGen(a.entity, attitude)
+	S.AddForceGen(a.entity, &BurnForceGen{
+		DV:       new(V3).Set(a.dv),
+		Start:    a.start,
+		Deadline: math.Max(a.start, S.WorldTime) + burnAlignTimeout,
+		attitude: attitude,
+	})
+	return nil
+}
+
+// BurnForceGen burns the ship's engine until the velocity change DV (ref
+// frame coordinates) is achieved, then releases the attitude autopilot
+// pointing the ship along DV.
+//
+// Thrust is cut when the Δv still to go along DV is what the engine
+// delivers while spooling down.  Close to the end the thrust is lowered
+// so the remaining Δv takes burnTailTime, which keeps the error of the
+// achieved Δv small compared to a frame at full thrust.
+type BurnForceGen struct {
+	DV    *V3
+	Start float64 // world time
+
+	// World time the burn fails if the ship is not yet aligned
+	Deadline float64
+
+	achieved V3
+	attitude *AttitudeForceGen
+	burning  bool
+	cut      bool
+	expired  bool
+}
+
+// Achieved returns the Δv achieved so far, in ref frame coordinates.
+func (g *BurnForceGen) Achieved() *V3 {
+	return new(V3).Set(&g.achieved)
+}
+
+func (g *BurnForceGen) UpdateForce(e Id, elapsed float64) (*V3, *V3) {
+	engine := S.Engine[e]
+	if engine == nil {
+		g.finish()
+		return nil, nil
+	}
+
+	dir := new(V3).Set(g.DV)
+	dir.Normalise()
+	togo := g.DV.Magnitude() - g.achieved.ScalarProduct(dir)
+	m := *S.Mass[e]
+
+	switch {
+	case !g.burning:
+		fv := S.Ori[e].ForwardVector()
+		aligned := math.Acos(math.Max(-1, math.Min(1, fv.ScalarProduct(dir)))) < maneuverAlignTolerance
+		if S.WorldTime > g.Deadline && !aligned {
+			postBurnEvent(e, EventBurnFailed)
+			g.finish()
+			return nil, nil
+		}
+		if S.WorldTime < g.Start || !aligned {
+			return nil, nil
+		}
+		g.burning = true
+		engine.SetThrust(engine.MaxThrust())
+	case !g.cut:
+		// Δv delivered while spooling down from the current thrust
+		var spoolDown float64
+		if me, ok := engine.(*MainEngine); ok {
+			t := engine.Thrust()
+			spoolDown = t / 2 * (me.Class.SpoolTimeBase() * t / engine.MaxThrust()) / m
+		}
+		thrust := math.Min(engine.MaxThrust(), togo*m/burnTailTime)
+		thrust = math.Max(engine.MinThrust(), thrust)
+
+		// cut if another frame at thrust would overshoot more than
+		// cutting now undershoots
+		if togo <= spoolDown+thrust*elapsed/m/2 {
+			g.cut = true
+			thrust = 0
+		}
+		engine.SetThrust(thrust)
+	}
+
+	force, torque := engine.Update(elapsed)
+	if g.cut && engine.Thrust() == 0 {
+		g.finish()
+	}
+	if force == nil {
+		return nil, nil
+	}
+	q := S.Ori[e]
+	force, torque = q.Rotate(force), q.Rotate(torque)
+	g.achieved.AddScaledVector(force, elapsed/m)
+	return force, torque
+}
+
+func (g *BurnForceGen) finish() {
+	g.expired = true
+	if g.attitude != nil {
+		g.attitude.Cancel()
+	}
+}
+
+func (g *BurnForceGen) IsExpired() bool {
+	return g.expired
+}
+
+func postBurnEvent(e Id, event string) {
+	msg, err := json.Marshal(&BurnEvent{e, event, S.WorldTime})
+	if err != nil {
+		log.Error("marshal burn event", "err", err)
+		return
+	}
+	S.MsgBus.Post(msg)
+}
@@ -0,0 +1,162 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestManeuverDeltaV(t *testing.T) {
+	e := devOrbitingShip()
+	o := S.Orb[e]
+	pos, vel := o.OrbitalToStateVector()
+
+	dv := o.ManeuverDeltaV(&V3{3, 4, 12})
+	if got := dv.Magnitude(); math.Abs(got-13) > 1e-9 {
+		t.Errorf("Δv magnitude %v, want 13", got)
+	}
+	vel.Normalise()
+	if got := dv.ScalarProduct(vel); math.Abs(got-3) > 1e-9 {
+		t.Errorf("prograde component %v, want 3", got)
+	}
+	normal := new(V3).VectorProduct(pos, vel)
+	normal.Normalise()
+	if got := dv.ScalarProduct(normal); math.Abs(got-4) > 1e-9 {
+		t.Errorf("normal component %v, want 4", got)
+	}
+
+	// a prograde impulse raises the opposite side of the orbit
+	after := o.AfterImpulse(o.ManeuverDeltaV(&V3{10, 0, 0}))
+	if after.Apoapsis() <= o.Apoapsis() {
+		t.Errorf("apoapsis %v not raised from %v", after.Apoapsis(), o.Apoapsis())
+	}
+}
+
+func TestManeuverExecution(t *testing.T) {
+	e := devOrbitingShip()
+	owner := common.HexToAddress("0x01")
+	S.SetOwner(e, owner)
+	S.Ori[e] = &Q{1, 0, 0, 0}
+	S.WorldTime = 0
+
+	// inclined circular low orbit
+	μ := S.Orb[e].μ
+	rp := S.PlanetInFrame(S.EntFrames[e]).Radius + 400e3
+	v := math.Sqrt(μ / rp)
+	S.Orb[e] = StateVectorToOrbital(&V3{rp, 0, 0}, &V3{0, v * math.Cos(0.3), v * math.Sin(0.3)}, μ)
+
+	ge := &GameEngine{}
+	results := make(chan *ActionResult, 4)
+	a := &ActionManeuver{entity: e, at: 300, dv: &V3{40, 0, 5}}
+	ge.executeAction(&ActionRequest{Id: 1, Action: a, Results: results, Signer: owner})
+	r := <-results
+	if r.Status != ActionApplied {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	plan := r.Data.(*ManeuverPlan)
+	if plan.Start >= 300 || plan.Start+plan.Duration <= 300 {
+		t.Errorf("burn %v+%v not centered on maneuver", plan.Start, plan.Duration)
+	}
+
+	var burn *BurnForceGen
+	var started, done float64
+	for wt := 1.0; wt < 1000; wt++ {
+		S.WorldTime = wt
+		ge.handleTimerActions()
+		updateClassicalMechanics(wt, 1.0, S.EntFrames[e], e)
+
+		for _, fg := range S.ForceGens[e] {
+			if b, ok := fg.(*BurnForceGen); ok {
+				burn = b
+			}
+		}
+		if burn != nil && burn.burning && started == 0 {
+			started = wt
+		}
+		if burn != nil && burn.IsExpired() {
+			done = wt
+			break
+		}
+	}
+	if done == 0 {
+		t.Fatalf("burn did not finish (started %v)", started)
+	}
+	if started < plan.Start {
+		t.Errorf("burn started at %v before %v", started, plan.Start)
+	}
+
+	achieved := burn.Achieved()
+	if err := new(V3).Sub(achieved, plan.DeltaV).Magnitude(); err > 0.05*plan.DeltaV.Magnitude() {
+		t.Errorf("achieved Δv %v off planned %v by %v", achieved.Fmt(), plan.DeltaV.Fmt(), err)
+	}
+
+	// the achieved orbit matches the prediction
+	got, want := S.Orb[e].SemimajorAxis(), plan.Orbit.SemimajorAxis()
+	if math.Abs(got-want)/want > 1e-3 {
+		t.Errorf("semimajor axis %v, predicted %v", got, want)
+	}
+	if math.Abs(S.Orb[e].e-plan.Orbit.e) > 5e-3 {
+		t.Errorf("eccentricity %v, predicted %v", S.Orb[e].e, plan.Orbit.e)
+	}
+}
+
+// A ship that never aligns gives up the burn, releasing the engine.
+func TestBurnAlignTimeout(t *testing.T) {
+	e := devOrbitingShip()
+	S.Ori[e] = &Q{1, 0, 0, 0}
+	S.WorldTime = 0
+	bus := S.MsgBus.Subscribe()
+
+	// Δv perpendicular to the ship's forward vector, and no autopilot
+	dv := new(V3).VectorProduct(S.Ori[e].ForwardVector(), &V3{1, 1, 1})
+	dv.Normalise()
+	dv.MulScalar(dv, 10)
+	if err := (&ActionBurn{e, dv, 10}).Execute(); err != nil {
+		t.Fatal(err)
+	}
+	cancelAttitude(e)
+
+	var failed float64
+	for wt := 1.0; wt < 1000 && failed == 0; wt++ {
+		S.WorldTime = wt
+		updateClassicalMechanics(wt, 1.0, S.EntFrames[e], e)
+		if !engineInUse(e) {
+			failed = wt
+		}
+	}
+	if failed != 10+burnAlignTimeout+1 {
+		t.Errorf("burn failed at %v", failed)
+	}
+
+	select {
+	case msg := <-bus:
+		ev := new(BurnEvent)
+		if err := json.Unmarshal(msg, ev); err != nil || ev.Event != EventBurnFailed || ev.Entity != e {
+			t.Errorf("unexpected event %s", msg)
+		}
+	default:
+		t.Errorf("no burn event")
+	}
+	if err := (&ActionEngineThrust{e, maxThrustBaseKestrel, 1}).Execute(); err != nil {
+		t.Error(err)
+	}
+}
@@ -229,13 +229,14 @@ func (q *Q) Normalise() {
 	q.K *= x
 }
 
+// Mul sets q to the Hamilton product q * a.
 func (q *Q) Mul(a *Q) *Q {
-	q.R = q.R*a.R - q.I*a.I - q.J*a.J - q.K*a.K
-	q.I = q.R*a.I + q.I*a.R + q.J*a.K - q.K*a.J
-	q.J = q.R*a.J + q.J*a.R + q.K*a.I - q.I*a.K
-	q.K = q.R*a.K + q.K*a.R + q.I*a.J - q.J*a.I
+	r := q.R*a.R - q.I*a.I - q.J*a.J - q.K*a.K
+	i := q.R*a.I + q.I*a.R + q.J*a.K - q.K*a.J
+	j := q.R*a.J + q.J*a.R + q.K*a.I - q.I*a.K
+	k := q.R*a.K + q.K*a.R + q.I*a.J - q.J*a.I
+	q.R, q.I, q.J, q.K = r, i, j, k
 	return q
-
 }
 
 func (q *Q) AddScaledVector(v *V3, s float64) *Q {
@@ -256,6 +257,237 @@ func (q *Q) ForwardVector() *V3 {
 	}
 }
 
+func (q *Q) Set(a *Q) *Q {
+	q.R, q.I, q.J, q.K = a.R, a.I, a.J, a.K
+	return q
+}
+
+func (q *Q) Magnitude() float64 {
+	return math.Sqrt(q.R*q.R + q.I*q.I + q.J*q.J + q.K*q.K)
+}
+
+func (q *Q) Dot(a *Q) float64 {
+	return q.R*a.R + q.I*a.I + q.J*a.J + q.K*a.K
+}
+
+// Conjugate sets q to the conjugate of a.  For unit quaternions the
+// conjugate is the inverse rotation.
+func (q *Q) Conjugate(a *Q) *Q {
+	q.R, q.I, q.J, q.K = a.R, -a.I, -a.J, -a.K
+	return q
+}
+
+// Inverse sets q to the inverse of a, or the no-rotation quaternion if a
+// has zero length.
+func (q *Q) Inverse(a *Q) *Q {
+	x := a.Dot(a)
+	if x < DBL_EPSILON {
+		q.R, q.I, q.J, q.K = 1, 0, 0, 0
+		return q
+	}
+	q.Conjugate(a)
+	q.R /= x
+	q.I /= x
+	q.J /= x
+	q.K /= x
+	return q
+}
+
+// Rotate returns v rotated by the (unit) quaternion q, i.e. q * v * q^-1.
+func (q *Q) Rotate(v *V3) *V3 {
+	// v' = v + 2r(u x v) + 2(u x (u x v)) where u is the vector part of q
+	u := &V3{q.I, q.J, q.K}
+	t := new(V3).VectorProduct(u, v)
+	t.MulScalar(t, 2)
+	res := new(V3).Set(v).AddScaledVector(t, q.R)
+	return res.Add(res, new(V3).VectorProduct(u, t))
+}
+
+// SetAxisAngle sets q to the rotation of angle radians around axis.
+// The axis does not need to be normalised.
+func (q *Q) SetAxisAngle(axis *V3, angle float64) *Q {
+	a := new(V3).Set(axis)
+	a.Normalise()
+	s := math.Sin(angle / 2)
+	q.R, q.I, q.J, q.K = math.Cos(angle/2), a.X*s, a.Y*s, a.Z*s
+	return q
+}
+
+// AxisAngle returns the unit rotation axis and the rotation angle in
+// radians (0 <= angle <= 2π) of the unit quaternion q.  The X axis is
+// returned for the no-rotation quaternion.
+func (q *Q) AxisAngle() (*V3, float64) {
+	r := math.Max(-1, math.Min(1, q.R))
+	angle := 2 * math.Acos(r)
+	s := math.Sqrt(1 - r*r)
+	if s < DBL_EPSILON {
+		return &V3{1, 0, 0}, angle
+	}
+	return &V3{q.I / s, q.J / s, q.K / s}, angle
+}
+
+// SetEuler sets q from Euler angles in radians: roll around the X axis,
+// pitch around the Y axis and yaw around the Z axis, applied in the
+// aerospace (Z-Y-X, intrinsic) order: yaw first, then pitch, then roll.
+func (q *Q) SetEuler(roll, pitch, yaw float64) *Q {
+	cr, sr := math.Cos(roll/2), math.Sin(roll/2)
+	cp, sp := math.Cos(pitch/2), math.Sin(pitch/2)
+	cy, sy := math.Cos(yaw/2), math.Sin(yaw/2)
+
+	q.R = cr*cp*cy + sr*sp*sy
+	q.I = sr*cp*cy - cr*sp*sy
+	q.J = cr*sp*cy + sr*cp*sy
+	q.K = cr*cp*sy - sr*sp*cy
+	return q
+}
+
+// Euler returns the roll, pitch and yaw angles in radians of the unit
+// quaternion q.  See SetEuler for the convention.  At pitch ±π/2
+// (gimbal lock) roll is returned as zero and yaw absorbs the rotation.
+func (q *Q) Euler() (float64, float64, float64) {
+	sp := 2 * (q.R*q.J - q.K*q.I)
+	if math.Abs(sp) >= 1-DBL_EPSILON {
+		pitch := math.Copysign(math.Pi/2, sp)
+		yaw := -2 * math.Copysign(1, sp) * math.Atan2(q.I, q.R)
+		return 0, pitch, yaw
+	}
+
+	roll := math.Atan2(2*(q.R*q.I+q.J*q.K), 1-2*(q.I*q.I+q.J*q.J))
+	pitch := math.Asin(sp)
+	yaw := math.Atan2(2*(q.R*q.K+q.I*q.J), 1-2*(q.J*q.J+q.K*q.K))
+	return roll, pitch, yaw
+}
+
+// M3 returns the rotation matrix of the unit quaternion q.
+// The matrix equals the rotation part of the transform matrix, see
+// updateTransformMatrix in physics.go.
+func (q *Q) M3() *M3 {
+	return &M3{
+		1 - 2*q.J*q.J - 2*q.K*q.K,
+		2*q.I*q.J - 2*q.R*q.K,
+		2*q.I*q.K + 2*q.R*q.J,
+
+		2*q.I*q.J + 2*q.R*q.K,
+		1 - 2*q.I*q.I - 2*q.K*q.K,
+		2*q.J*q.K - 2*q.R*q.I,
+
+		2*q.I*q.K - 2*q.R*q.J,
+		2*q.J*q.K + 2*q.R*q.I,
+		1 - 2*q.I*q.I - 2*q.J*q.J,
+	}
+}
+
+// SetM3 sets q to the rotation of the (orthonormal) rotation matrix m.
+// See https://www.euclideanspace.com/maths/geometry/rotations/conversions/matrixToQuaternion/
+func (q *Q) SetM3(m *M3) *Q {
+	trace := m[0] + m[4] + m[8]
+	switch {
+	case trace > 0:
+		s := 0.5 / math.Sqrt(trace+1)
+		q.R = 0.25 / s
+		q.I = (m[7] - m[5]) * s
+		q.J = (m[2] - m[6]) * s
+		q.K = (m[3] - m[1]) * s
+	case m[0] > m[4] && m[0] > m[8]:
+		s := 2 * math.Sqrt(1+m[0]-m[4]-m[8])
+		q.R = (m[7] - m[5]) / s
+		q.I = 0.25 * s
+		q.J = (m[1] + m[3]) / s
+		q.K = (m[2] + m[6]) / s
+	case m[4] > m[8]:
+		s := 2 * math.Sqrt(1+m[4]-m[0]-m[8])
+		q.R = (m[2] - m[6]) / s
+		q.I = (m[1] + m[3]) / s
+		q.J = 0.25 * s
+		q.K = (m[5] + m[7]) / s
+	default:
+		s := 2 * math.Sqrt(1+m[8]-m[0]-m[4])
+		q.R = (m[3] - m[1]) / s
+		q.I = (m[2] + m[6]) / s
+		q.J = (m[5] + m[7]) / s
+		q.K = 0.25 * s
+	}
+	q.Normalise()
+	return q
+}
+
+// SetLookRotation sets q to the orientation with the forward vector (see
+// ForwardVector) pointing along forward and the body Y axis as close as
+// possible to up.  If forward and up are parallel, an arbitrary up
+// perpendicular to forward is used.
+func (q *Q) SetLookRotation(forward, up *V3) *Q {
+	z := new(V3).Set(forward)
+	z.Normalise()
+
+	x := new(V3).VectorProduct(up, z)
+	if x.SquareMagnitude() < DBL_EPSILON {
+		// pick the world axis least aligned with forward
+		alt := &V3{1, 0, 0}
+		if math.Abs(z.X) > 0.9 {
+			alt = &V3{0, 1, 0}
+		}
+		x.VectorProduct(alt, z)
+	}
+	x.Normalise()
+	y := new(V3).VectorProduct(z, x)
+
+	// the body axes are the columns of the rotation matrix
+	return q.SetM3(&M3{
+		x.X, y.X, z.X,
+		x.Y, y.Y, z.Y,
+		x.Z, y.Z, z.Z,
+	})
+}
+
+// Nlerp sets q to the normalised linear interpolation between unit
+// quaternions a and b, following the shortest path.
+func (q *Q) Nlerp(a, b *Q, t float64) *Q {
+	s := 1.0
+	if a.Dot(b) < 0 {
+		s = -1.0
+	}
+	q.R = a.R + (s*b.R-a.R)*t
+	q.I = a.I + (s*b.I-a.I)*t
+	q.J = a.J + (s*b.J-a.J)*t
+	q.K = a.K + (s*b.K-a.K)*t
+	q.Normalise()
+	return q
+}
+
+// Slerp sets q to the spherical linear interpolation between unit
+// quaternions a and b, following the shortest path.
+// See https://en.wikipedia.org/wiki/Slerp
+func (q *Q) Slerp(a, b *Q, t float64) *Q {
+	b2 := new(Q).Set(b)
+	d := a.Dot(b)
+	if d < 0 {
+		b2.R, b2.I, b2.J, b2.K = -b.R, -b.I, -b.J, -b.K
+		d = -d
+	}
+
+	// fall back to nlerp for nearly identical orientations
+	if d > 1-slerpThreshold {
+		return q.Nlerp(a, b2, t)
+	}
+
+	θ := math.Acos(d)
+	sθ := math.Sin(θ)
+	wa := math.Sin((1-t)*θ) / sθ
+	wb := math.Sin(t*θ) / sθ
+	q.R = wa*a.R + wb*b2.R
+	q.I = wa*a.I + wb*b2.I
+	q.J = wa*a.J + wb*b2.J
+	q.K = wa*a.K + wb*b2.K
+	return q
+}
+
+// AngleTo returns the angle in radians (0 <= angle <= π) of the smallest
+// rotation between the orientations of unit quaternions q and a.
+func (q *Q) AngleTo(a *Q) float64 {
+	d := math.Min(1, math.Abs(q.Dot(a)))
+	return 2 * math.Acos(d)
+}
+
 func RadToDeg(radians float64) float64 {
 	return radians * (180 / math.Pi)
 }
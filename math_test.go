@@ -0,0 +1,252 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+// Property tests for the quaternion toolkit.  Each property is checked
+// against a deterministic set of random rotations.
+
+const (
+	quatTestCount     = 1000
+	quatTestTolerance = 1e-9
+)
+
+func randUnitQ() *Q {
+	axis := &V3{Rand.Float64()*2 - 1, Rand.Float64()*2 - 1, Rand.Float64()*2 - 1}
+	return new(Q).SetAxisAngle(axis, Rand.Float64()*twoPi)
+}
+
+func randV3() *V3 {
+	return &V3{Rand.Float64()*200 - 100, Rand.Float64()*200 - 100, Rand.Float64()*200 - 100}
+}
+
+func v3Equal(a, b *V3, tol float64) bool {
+	return new(V3).Sub(a, b).Magnitude() <= tol*math.Max(1, a.Magnitude())
+}
+
+// same rotation: q and -q represent the same orientation
+func sameRotation(a, b *Q) bool {
+	return math.Abs(math.Abs(a.Dot(b))-1) < quatTestTolerance
+}
+
+func seedQuatTests() {
+	rnd, _ := NewRand(42)
+	Rand = rnd
+}
+
+func TestQuatMul(t *testing.T) {
+	// i * j = k, j * k = i, k * i = j, i * i = -1
+	i, j, k := &Q{0, 1, 0, 0}, &Q{0, 0, 1, 0}, &Q{0, 0, 0, 1}
+	if got := new(Q).Set(i).Mul(j); *got != *k {
+		t.Errorf("i*j: got %v want %v", got, k)
+	}
+	if got := new(Q).Set(j).Mul(k); *got != *i {
+		t.Errorf("j*k: got %v want %v", got, i)
+	}
+	if got := new(Q).Set(k).Mul(i); *got != *j {
+		t.Errorf("k*i: got %v want %v", got, j)
+	}
+	if got := new(Q).Set(i).Mul(i); *got != (Q{-1, 0, 0, 0}) {
+		t.Errorf("i*i: got %v want -1", got)
+	}
+
+	// composition: rotating by a*b equals rotating by b then a
+	seedQuatTests()
+	for n := 0; n < quatTestCount; n++ {
+		a, b, v := randUnitQ(), randUnitQ(), randV3()
+		ab := new(Q).Set(a).Mul(b)
+		if !v3Equal(ab.Rotate(v), a.Rotate(b.Rotate(v)), quatTestTolerance) {
+			t.Fatalf("composition mismatch for a %v b %v", a, b)
+		}
+		if math.Abs(ab.Magnitude()-1) > quatTestTolerance {
+			t.Fatalf("unit norm not preserved: %v", ab.Magnitude())
+		}
+	}
+}
+
+func TestQuatInverse(t *testing.T) {
+	seedQuatTests()
+	for n := 0; n < quatTestCount; n++ {
+		q, v := randUnitQ(), randV3()
+		inv := new(Q).Inverse(q)
+		if !sameRotation(new(Q).Set(q).Mul(inv), &Q{1, 0, 0, 0}) {
+			t.Fatalf("q * q^-1 != 1 for %v", q)
+		}
+		if !v3Equal(inv.Rotate(q.Rotate(v)), v, quatTestTolerance) {
+			t.Fatalf("inverse rotation mismatch for %v", q)
+		}
+
+		// non-unit quaternion
+		s := &Q{q.R * 3, q.I * 3, q.J * 3, q.K * 3}
+		if !sameRotation(s.Mul(new(Q).Inverse(s)), &Q{1, 0, 0, 0}) {
+			t.Fatalf("s * s^-1 != 1 for %v", s)
+		}
+	}
+}
+
+func TestQuatRotate(t *testing.T) {
+	// 90 degrees around Z rotates X to Y
+	q := new(Q).SetAxisAngle(&V3{0, 0, 1}, math.Pi/2)
+	if got := q.Rotate(&V3{1, 0, 0}); !v3Equal(got, &V3{0, 1, 0}, quatTestTolerance) {
+		t.Errorf("got %v want %v", got, V3{0, 1, 0})
+	}
+
+	seedQuatTests()
+	for n := 0; n < quatTestCount; n++ {
+		q, v := randUnitQ(), randV3()
+		r := q.Rotate(v)
+		if math.Abs(r.Magnitude()-v.Magnitude()) > quatTestTolerance*v.Magnitude() {
+			t.Fatalf("rotation changed length: %v -> %v", v.Magnitude(), r.Magnitude())
+		}
+		if !v3Equal(r, q.M3().Transform(v), quatTestTolerance) {
+			t.Fatalf("Rotate and M3 disagree for %v", q)
+		}
+		if !v3Equal(q.Rotate(&V3{0, 0, 1}), q.ForwardVector(), quatTestTolerance) {
+			t.Fatalf("Rotate and ForwardVector disagree for %v", q)
+		}
+	}
+}
+
+func TestQuatAxisAngleRoundTrip(t *testing.T) {
+	seedQuatTests()
+	for n := 0; n < quatTestCount; n++ {
+		q := randUnitQ()
+		axis, angle := q.AxisAngle()
+		if math.Abs(axis.Magnitude()-1) > quatTestTolerance {
+			t.Fatalf("axis not unit length: %v", axis.Magnitude())
+		}
+		if !sameRotation(new(Q).SetAxisAngle(axis, angle), q) {
+			t.Fatalf("axis-angle round trip mismatch for %v", q)
+		}
+	}
+}
+
+func TestQuatEulerRoundTrip(t *testing.T) {
+	seedQuatTests()
+	for n := 0; n < quatTestCount; n++ {
+		q := randUnitQ()
+		roll, pitch, yaw := q.Euler()
+		if !sameRotation(new(Q).SetEuler(roll, pitch, yaw), q) {
+			t.Fatalf("euler round trip mismatch for %v", q)
+		}
+	}
+
+	// gimbal lock
+	for _, pitch := range []float64{math.Pi / 2, -math.Pi / 2} {
+		q := new(Q).SetEuler(0.3, pitch, 1.1)
+		roll, pitch2, yaw := q.Euler()
+		if !sameRotation(new(Q).SetEuler(roll, pitch2, yaw), q) {
+			t.Errorf("gimbal lock round trip mismatch for pitch %v", pitch)
+		}
+	}
+
+	// single axis rotations
+	q := new(Q).SetEuler(0, 0, math.Pi/2)
+	if got := q.Rotate(&V3{1, 0, 0}); !v3Equal(got, &V3{0, 1, 0}, quatTestTolerance) {
+		t.Errorf("yaw: got %v want %v", got, V3{0, 1, 0})
+	}
+}
+
+func TestQuatM3RoundTrip(t *testing.T) {
+	seedQuatTests()
+	for n := 0; n < quatTestCount; n++ {
+		q := randUnitQ()
+		q2 := new(Q).SetM3(q.M3())
+		if !sameRotation(q, q2) {
+			t.Fatalf("matrix round trip mismatch: %v -> %v", q, q2)
+		}
+		if math.Abs(q2.Magnitude()-1) > quatTestTolerance {
+			t.Fatalf("unit norm not preserved: %v", q2.Magnitude())
+		}
+	}
+}
+
+func TestQuatLookRotation(t *testing.T) {
+	seedQuatTests()
+	for n := 0; n < quatTestCount; n++ {
+		forward, up := randV3(), randV3()
+		q := new(Q).SetLookRotation(forward, up)
+
+		forward.Normalise()
+		if !v3Equal(q.ForwardVector(), forward, quatTestTolerance) {
+			t.Fatalf("forward mismatch: got %v want %v", q.ForwardVector(), forward)
+		}
+		// body Y axis lies in the plane of forward and up, on the up side
+		bodyUp := q.Rotate(&V3{0, 1, 0})
+		if bodyUp.ScalarProduct(up) < 0 {
+			t.Fatalf("body up points away from up")
+		}
+		normal := new(V3).VectorProduct(forward, up)
+		if math.Abs(bodyUp.ScalarProduct(normal)) > quatTestTolerance*normal.Magnitude() {
+			t.Fatalf("body up not in forward/up plane")
+		}
+	}
+
+	// parallel forward and up
+	q := new(Q).SetLookRotation(&V3{0, 0, 5}, &V3{0, 0, 1})
+	if !v3Equal(q.ForwardVector(), &V3{0, 0, 1}, quatTestTolerance) {
+		t.Errorf("parallel up: got forward %v", q.ForwardVector())
+	}
+}
+
+func TestQuatInterpolation(t *testing.T) {
+	seedQuatTests()
+	for n := 0; n < quatTestCount; n++ {
+		a, b := randUnitQ(), randUnitQ()
+		if !sameRotation(new(Q).Slerp(a, b, 0), a) || !sameRotation(new(Q).Slerp(a, b, 1), b) {
+			t.Fatalf("slerp endpoints mismatch")
+		}
+		if !sameRotation(new(Q).Nlerp(a, b, 0), a) || !sameRotation(new(Q).Nlerp(a, b, 1), b) {
+			t.Fatalf("nlerp endpoints mismatch")
+		}
+
+		// slerp has constant angular velocity
+		total := a.AngleTo(b)
+		tt := Rand.Float64()
+		s := new(Q).Slerp(a, b, tt)
+		if math.Abs(s.Magnitude()-1) > quatTestTolerance {
+			t.Fatalf("slerp unit norm not preserved: %v", s.Magnitude())
+		}
+		if math.Abs(a.AngleTo(s)-tt*total) > 1e-6 {
+			t.Fatalf("slerp angle: got %v want %v", a.AngleTo(s), tt*total)
+		}
+
+		nl := new(Q).Nlerp(a, b, tt)
+		if math.Abs(nl.Magnitude()-1) > quatTestTolerance {
+			t.Fatalf("nlerp unit norm not preserved: %v", nl.Magnitude())
+		}
+	}
+}
+
+func TestQuatAngleTo(t *testing.T) {
+	a := &Q{1, 0, 0, 0}
+	b := new(Q).SetAxisAngle(&V3{1, 1, 0}, 0.7)
+	if got := a.AngleTo(b); math.Abs(got-0.7) > quatTestTolerance {
+		t.Errorf("got %v want %v", got, 0.7)
+	}
+
+	// the shortest rotation is used
+	c := new(Q).SetAxisAngle(&V3{1, 1, 0}, twoPi-0.7)
+	if got := a.AngleTo(c); math.Abs(got-0.7) > quatTestTolerance {
+		t.Errorf("got %v want %v", got, 0.7)
+	}
+}
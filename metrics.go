@@ -0,0 +1,63 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"sync/atomic"
+)
+
+// EngineMetrics holds counters of the game engine.  The engine loop
+// updates them while other goroutines (e.g. connection handlers) may read
+// them, so all access goes through sync/atomic.
+type EngineMetrics struct {
+	Frames          uint64
+	ActionsApplied  uint64
+	ActionsRejected uint64
+
+	// ActionsUnauthorized counts rejections by failed authorization; they
+	// are also included in ActionsRejected
+	ActionsUnauthorized uint64
+}
+
+func (m *EngineMetrics) frame() {
+	atomic.AddUint64(&m.Frames, 1)
+}
+
+func (m *EngineMetrics) actionApplied() {
+	atomic.AddUint64(&m.ActionsApplied, 1)
+}
+
+func (m *EngineMetrics) actionRejected() {
+	atomic.AddUint64(&m.ActionsRejected, 1)
+}
+
+func (m *EngineMetrics) actionUnauthorized() {
+	atomic.AddUint64(&m.ActionsUnauthorized, 1)
+	atomic.AddUint64(&m.ActionsRejected, 1)
+}
+
+// Snapshot returns a consistent-per-counter copy of the metrics.
+func (m *EngineMetrics) Snapshot() EngineMetrics {
+	return EngineMetrics{
+		Frames:          atomic.LoadUint64(&m.Frames),
+		ActionsApplied:  atomic.LoadUint64(&m.ActionsApplied),
+		ActionsRejected: atomic.LoadUint64(&m.ActionsRejected),
+
+		ActionsUnauthorized: atomic.LoadUint64(&m.ActionsUnauthorized),
+	}
+}
@@ -17,20 +17,51 @@
 */
 package tesseract
 
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
 // MessageBus is a channel-based message / event bus where systems
 // post messages delivered to all subscribers.
 type MessageBus struct {
-	channels []chan<- []byte
+	mu       sync.Mutex
+	channels []chan []byte
 }
 
 func (mb *MessageBus) Subscribe() <-chan []byte {
-	c := make(chan []byte, 10)
+	c := make(chan []byte, msgBusBufferSize)
+	mb.mu.Lock()
 	mb.channels = append(mb.channels, c)
+	mb.mu.Unlock()
 	return c
 }
 
+// Unsubscribe stops the delivery of messages to the subscriber channel c.
+// Subscribers must unsubscribe once they stop reading c.
+func (mb *MessageBus) Unsubscribe(c <-chan []byte) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	for i, ch := range mb.channels {
+		if (<-chan []byte)(ch) == c {
+			mb.channels = append(mb.channels[:i], mb.channels[i+1:]...)
+			return
+		}
+	}
+}
+
+// Post delivers the message to all subscribers.  Messages are dropped
+// for subscribers not keeping up rather than stalling the poster, e.g.
+// the game engine.
 func (mb *MessageBus) Post(msg []byte) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
 	for _, c := range mb.channels {
-		c <- msg
+		select {
+		case c <- msg:
+		default:
+			log.Warn("message bus message dropped", "len", len(msg))
+		}
 	}
 }
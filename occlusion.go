@@ -0,0 +1,275 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"sort"
+)
+
+// Geometric occlusion (line-of-sight) queries between entities and the
+// spherical bodies of the ref frame tree.  Used for e.g. comm links,
+// sensor locks and solar power.
+//
+// Bodies are modelled as spheres and light sources as points, which means
+// eclipses are umbral only (no penumbra).
+
+// Occluder is a spherical body that can block line of sight.
+// Pos is relative to the origin of the ref frame the occluder was
+// queried in.
+type Occluder struct {
+	Entity Id
+	Pos    *V3
+	Radius float64
+}
+
+// Eclipse holds the entry and exit times, in seconds from the orbit's
+// current position, of one passage through a body's shadow.
+type Eclipse struct {
+	Entry, Exit float64
+}
+
+// SegmentIntersectsSphere returns whether the line segment from a to b
+// intersects the sphere with center c and radius r.
+func SegmentIntersectsSphere(a, b, c *V3, r float64) bool {
+	closest := closestPointOnSegment(a, b, c)
+	return new(V3).Sub(c, closest).SquareMagnitude() < r*r
+}
+
+// closestPointOnSegment returns the point of the line segment from a to b
+// closest to point c.
+func closestPointOnSegment(a, b, c *V3) *V3 {
+	ab := new(V3).Sub(b, a)
+	ac := new(V3).Sub(c, a)
+
+	// closest point as fraction of ab
+	t := 0.0
+	if l := ab.SquareMagnitude(); l > 0 {
+		t = math.Max(0, math.Min(1, ac.ScalarProduct(ab)/l))
+	}
+	return new(V3).Set(a).AddScaledVector(ab, t)
+}
+
+// Occluders returns all planets and stars in the star system of ref frame
+// rf, positioned relative rf at dt seconds from now, ordered by entity id.
+// If atmosphere is true, the radius of planets with an atmosphere includes
+// the atmosphere height.
+func Occluders(rf *RefFrame, dt float64, atmosphere bool) []*Occluder {
+	system := rf.system()
+	occluders := []*Occluder{}
+
+	for e, p := range S.Planets {
+		prf := S.EntFrames[e]
+		if prf == nil || prf.system() != system {
+			continue
+		}
+		r := p.Radius
+		if atmosphere && p.Atmosphere != nil {
+			r += p.Atmosphere.Height
+		}
+		pos, _ := prf.StateIn(rf, dt)
+		occluders = append(occluders, &Occluder{e, pos, r})
+	}
+
+	for e, st := range S.StarsById {
+		srf := S.EntFrames[e]
+		if srf == nil || srf.system() != system {
+			continue
+		}
+		pos, _ := srf.StateIn(rf, dt)
+		occluders = append(occluders, &Occluder{e, pos, st.Body.Radius})
+	}
+
+	sort.Slice(occluders, func(i, j int) bool {
+		return occluders[i].Entity < occluders[j].Entity
+	})
+	return occluders
+}
+
+// LineOfSight returns whether entities e1 and e2 can see each other past
+// the bodies of their star system(s).  If not, the id of the blocking body
+// closest to e1 is also returned.  Entities not in a ref frame, e.g. unknown
+// or removed ones, have no line of sight.
+func LineOfSight(e1, e2 Id, atmosphere bool) (bool, Id) {
+	return lineOfSightAt(e1, e2, 0, atmosphere)
+}
+
+func lineOfSightAt(e1, e2 Id, dt float64, atmosphere bool) (bool, Id) {
+	rf, rf2 := S.EntFrames[e1], S.EntFrames[e2]
+	if rf == nil || rf2 == nil {
+		return false, 0
+	}
+	a, _ := S.EntityState(e1, dt)
+	b := entityPosIn(e2, rf, dt)
+
+	frames := []*RefFrame{rf}
+	if rf2.system() != rf.system() {
+		frames = append(frames, rf2)
+	}
+
+	occluders := []*Occluder{}
+	for _, f := range frames {
+		offset, _ := f.StateIn(rf, dt)
+		for _, o := range Occluders(f, dt, atmosphere) {
+			o.Pos.Add(o.Pos, offset)
+			occluders = append(occluders, o)
+		}
+	}
+
+	var blocker *Occluder
+	closest := math.Inf(1)
+	for _, o := range occluders {
+		if o.Entity == e1 || o.Entity == e2 {
+			continue
+		}
+		if !SegmentIntersectsSphere(a, b, o.Pos, o.Radius) {
+			continue
+		}
+		if d := new(V3).Sub(o.Pos, a).SquareMagnitude(); d < closest {
+			blocker, closest = o, d
+		}
+	}
+	if blocker != nil {
+		return false, blocker.Entity
+	}
+	return true, 0
+}
+
+// InShadow returns whether the entity is in the shadow of a body, blocking
+// the light of the star of its star system.  If so, the id of the
+// shadowing body is also returned.
+func InShadow(e Id, atmosphere bool) (bool, Id) {
+	star := systemStar(S.EntFrames[e])
+	if star == nil {
+		return false, 0
+	}
+	visible, by := LineOfSight(e, star.Entity, atmosphere)
+	return !visible, by
+}
+
+// findCrossings returns the times during the next span seconds at which f
+// changes value, in time order.  f is sampled samples times per period (or
+// per span, if shorter) and each change refined by bisection to within
+// tolerance.  Changes closer together in time than the sample interval may
+// be missed.  Callers bound span to a number of periods.
+func findCrossings(f func(t float64) bool, span, period float64, samples int, tolerance float64) []float64 {
+	step := span / float64(samples)
+	if period < span {
+		step = period / float64(samples)
+	}
+
+	crossings := []float64{}
+	prev, was := 0.0, f(0)
+	for t := step; prev < span; t += step {
+		t = math.Min(t, span)
+		if t <= prev {
+			// step too small to advance t
+			break
+		}
+		v := f(t)
+		if v != was {
+			t0, t1 := prev, t
+			for t1-t0 > tolerance {
+				mid := t0 + (t1-t0)/2
+				if f(mid) == was {
+					t0 = mid
+				} else {
+					t1 = mid
+				}
+			}
+			crossings = append(crossings, t0+(t1-t0)/2)
+		}
+		was = v
+		prev = t
+	}
+	return crossings
+}
+
+// Eclipses returns the eclipses during the next span seconds of the orbit,
+// caused by the orbit's primary body of radius r.  The light source is at
+// position sun relative the primary and assumed stationary for the span.
+// Entry and exit times are found by findCrossings.  The span is capped at
+// eclipseMaxPeriods orbital periods.
+func (o *OE) Eclipses(sun *V3, r, span float64) []Eclipse {
+	span = math.Min(span, eclipseMaxPeriods*o.Period())
+	inShadow := func(t float64) bool {
+		pos, _ := o.AtTime(t).OrbitalToStateVector()
+		return SegmentIntersectsSphere(pos, sun, &V3{}, r)
+	}
+
+	// the shadow state alternates at each crossing
+	times := findCrossings(inShadow, span, o.Period(), eclipseSamplesPerOrbit, eclipseTimeTolerance)
+	if inShadow(0) {
+		times = append([]float64{0}, times...)
+	}
+	if len(times)%2 == 1 {
+		times = append(times, span)
+	}
+
+	eclipses := []Eclipse{}
+	for i := 0; i < len(times); i += 2 {
+		eclipses = append(eclipses, Eclipse{Entry: times[i], Exit: times[i+1]})
+	}
+	return eclipses
+}
+
+// EntityEclipses returns the eclipses during the next span seconds, at most
+// eclipseMaxPeriods orbital periods, of the entity's orbit, caused by the
+// planet the entity orbits.
+func EntityEclipses(e Id, span float64, atmosphere bool) []Eclipse {
+	rf := S.EntFrames[e]
+	planet := S.PlanetInFrame(rf)
+	star := systemStar(rf)
+	if S.Orb[e] == nil || planet == nil || star == nil {
+		return []Eclipse{}
+	}
+
+	r := planet.Radius
+	if atmosphere && planet.Atmosphere != nil {
+		r += planet.Atmosphere.Height
+	}
+	sun, _ := S.EntFrames[star.Entity].StateIn(rf, 0)
+	return S.Orb[e].Eclipses(sun, r, span)
+}
+
+// entityPosIn returns the position of the entity relative the origin of
+// ref frame rf.  Planets and stars are at the origin of their own frames.
+func entityPosIn(e Id, rf *RefFrame, dt float64) *V3 {
+	pos, _ := entityStateIn(e, rf, dt)
+	return pos
+}
+
+// entityStateIn returns the position and velocity of the entity relative
+// the origin of ref frame rf, dt seconds from now.
+func entityStateIn(e Id, rf *RefFrame, dt float64) (*V3, *V3) {
+	erf := S.EntFrames[e]
+	offset, offsetVel := erf.StateIn(rf, dt)
+	if S.Planets[e] != nil || S.StarsById[e] != nil {
+		return offset, offsetVel
+	}
+	pos, vel := S.EntityState(e, dt)
+	return pos.Add(pos, offset), vel.Add(vel, offsetVel)
+}
+
+// systemStar returns the star of the star system containing ref frame rf.
+func systemStar(rf *RefFrame) *Star {
+	if rf == nil || rf.IsRoot() {
+		return nil
+	}
+	return S.StarInFrame(rf.system())
+}
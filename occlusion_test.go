@@ -0,0 +1,245 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSegmentIntersectsSphere(t *testing.T) {
+	c := &V3{0, 0, 0}
+	cases := []struct {
+		Name string
+		A, B *V3
+		Hit  bool
+	}{
+		{"through center", &V3{-2, 0, 0}, &V3{2, 0, 0}, true},
+		{"passing outside", &V3{-2, 1.5, 0}, &V3{2, 1.5, 0}, false},
+		{"ending before sphere", &V3{-4, 0, 0}, &V3{-2, 0, 0}, false},
+		{"starting inside", &V3{0.5, 0, 0}, &V3{4, 4, 4}, true},
+		{"grazing", &V3{-2, 0.999, 0}, &V3{2, 0.999, 0}, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := SegmentIntersectsSphere(tc.A, tc.B, c, 1.0); got != tc.Hit {
+				t.Errorf("got %v want %v", got, tc.Hit)
+			}
+		})
+	}
+}
+
+func TestEclipsesCircular(t *testing.T) {
+	// Circular, equatorial orbit at 7000 km around an earth sized body,
+	// lit by a distant light source along the +X axis.  The orbiter starts
+	// at periapsis on the +X axis (fully lit).
+	R := 6378.0
+	r := 7000.0
+	μ := 398600.0
+	o := &OE{h: math.Sqrt(r * μ), μ: μ}
+	sun := &V3{1.5e8, 0, 0}
+
+	period := o.Period()
+	eclipses := o.Eclipses(sun, R, period)
+	if len(eclipses) != 1 {
+		t.Fatalf("eclipse count: got %d want 1", len(eclipses))
+	}
+
+	// For a (nearly) infinitely distant light source the shadow is a
+	// cylinder of radius R.
+	halfAngle := math.Asin(R / r)
+	exEntry := (math.Pi - halfAngle) / twoPi * period
+	exExit := (math.Pi + halfAngle) / twoPi * period
+
+	// the light source is not infinitely far away; allow for a few seconds
+	if math.Abs(eclipses[0].Entry-exEntry) > 2 || math.Abs(eclipses[0].Exit-exExit) > 2 {
+		t.Errorf("got %+v want entry %v exit %v", eclipses[0], exEntry, exExit)
+	}
+
+	// long spans are capped at eclipseMaxPeriods periods
+	eclipses = o.Eclipses(sun, R, 1e12)
+	if len(eclipses) != eclipseMaxPeriods {
+		t.Errorf("eclipse count over a long span: got %d want %d", len(eclipses), eclipseMaxPeriods)
+	}
+}
+
+// testOcclusionScene returns the frames of two planets on circular orbits
+// of a sun, on opposite sides at 1 AU: frame a on the +X and b on the -X
+// axis.
+func testOcclusionScene() (*RefFrame, *RefFrame, *Star) {
+	ResetState()
+	Rand, _ = NewRand(1)
+	star := NewStar(1.0)
+	star.Entity = S.NewEntity()
+	GetSector(&V3{}).addStarFixed(star, &V3{})
+	starRF := S.EntFrames[star.Entity]
+
+	μ := GravitationalConstant * solarMass
+	v := math.Sqrt(μ / aum)
+	a := &RefFrame{Parent: starRF, Orbit: StateVectorToOrbital(&V3{aum, 0, 0}, &V3{0, v, 0}, μ)}
+	b := &RefFrame{Parent: starRF, Orbit: StateVectorToOrbital(&V3{-aum, 0, 0}, &V3{0, -v, 0}, μ)}
+	for _, rf := range []*RefFrame{a, b} {
+		p := testPlanet()
+		p.Entity = S.NewEntity()
+		S.AddPlanet(p, rf)
+	}
+	return a, b, star
+}
+
+// testOccluded returns a new entity at rest at pos in ref frame rf.
+func testOccluded(rf *RefFrame, pos *V3) Id {
+	e := S.NewEntity()
+	S.EntFrames[e] = rf
+	S.Pos[e] = pos
+	return e
+}
+
+func TestStateInSiblingFrames(t *testing.T) {
+	a, b, _ := testOcclusionScene()
+	for _, dt := range []float64{0, 1e6} {
+		posA, velA := a.LocalState(dt)
+		posB, velB := b.LocalState(dt)
+		exPos, exVel := new(V3).Sub(posB, posA), new(V3).Sub(velB, velA)
+
+		pos, vel := b.StateIn(a, dt)
+		if new(V3).Sub(pos, exPos).Magnitude() > 1e-3 || new(V3).Sub(vel, exVel).Magnitude() > 1e-9 {
+			t.Errorf("dt %v: got %v %v want %v %v", dt, pos.Fmt(), vel.Fmt(), exPos.Fmt(), exVel.Fmt())
+		}
+		back, _ := a.StateIn(b, dt)
+		if new(V3).Add(pos, back).Magnitude() > 1e-3 {
+			t.Errorf("dt %v: a in b %v not opposite b in a %v", dt, back.Fmt(), pos.Fmt())
+		}
+	}
+	if pos, _ := b.StateIn(a, 0); math.Abs(pos.X+2*aum) > 1e-3 {
+		t.Errorf("b at %v relative a", pos.Fmt())
+	}
+}
+
+func TestLineOfSight(t *testing.T) {
+	a, b, star := testOcclusionScene()
+	planetA := S.PlanetInFrame(a)
+	R := planetA.Radius
+
+	// both sides of planet a
+	e1 := testOccluded(a, &V3{2 * R, 0, 0})
+	e2 := testOccluded(a, &V3{-2 * R, 0, 0})
+	if visible, by := LineOfSight(e1, e2, false); visible || by != planetA.Entity {
+		t.Errorf("line of sight through planet: %v %v", visible, by)
+	}
+
+	// across the star system, through and past the star
+	e3 := testOccluded(b, &V3{0, 0, 2 * R})
+	e4 := testOccluded(a, &V3{0, 0, 2 * R})
+	if visible, by := LineOfSight(e3, e4, false); visible || by != star.Entity {
+		t.Errorf("line of sight through star: %v %v", visible, by)
+	}
+	e5 := testOccluded(b, &V3{0, 0, 10 * star.Body.Radius})
+	e6 := testOccluded(a, &V3{0, 0, 10 * star.Body.Radius})
+	if visible, by := LineOfSight(e5, e6, false); !visible || by != 0 {
+		t.Errorf("no line of sight past star: %v %v", visible, by)
+	}
+
+	// grazing the atmosphere
+	h := planetA.Atmosphere.Height / 2
+	e7 := testOccluded(a, &V3{-2 * R, R + h, 0})
+	e8 := testOccluded(a, &V3{2 * R, R + h, 0})
+	if visible, _ := LineOfSight(e7, e8, false); !visible {
+		t.Errorf("no line of sight above surface")
+	}
+	if visible, by := LineOfSight(e7, e8, true); visible || by != planetA.Entity {
+		t.Errorf("line of sight through atmosphere: %v %v", visible, by)
+	}
+
+	// through both planets and the star: the blocker closest to e1
+	planetB := S.PlanetInFrame(b)
+	e9 := testOccluded(b, &V3{-2 * R, 0, 0})
+	if visible, by := LineOfSight(e1, e9, false); visible || by != planetA.Entity {
+		t.Errorf("line of sight through system blocked by %v, expected %v", by, planetA.Entity)
+	}
+	if visible, by := LineOfSight(e9, e1, false); visible || by != planetB.Entity {
+		t.Errorf("line of sight through system blocked by %v, expected %v", by, planetB.Entity)
+	}
+
+	// entities without a ref frame
+	unknown := S.NewEntity()
+	if visible, by := LineOfSight(e1, unknown, false); visible || by != 0 {
+		t.Errorf("line of sight to unknown entity: %v %v", visible, by)
+	}
+	if visible, by := LineOfSight(unknown, e1, false); visible || by != 0 {
+		t.Errorf("line of sight from unknown entity: %v %v", visible, by)
+	}
+	if shadow, by := InShadow(unknown, false); shadow || by != 0 {
+		t.Errorf("unknown entity in shadow: %v %v", shadow, by)
+	}
+}
+
+func TestInShadow(t *testing.T) {
+	a, _, star := testOcclusionScene()
+	planetA := S.PlanetInFrame(a)
+	R := planetA.Radius
+
+	// the sun is on the -X side of planet a
+	night := testOccluded(a, &V3{2 * R, 0, 0})
+	day := testOccluded(a, &V3{-2 * R, 0, 0})
+	if shadow, by := InShadow(night, false); !shadow || by != planetA.Entity {
+		t.Errorf("night side not in shadow: %v %v", shadow, by)
+	}
+	if shadow, _ := InShadow(day, false); shadow {
+		t.Errorf("day side in shadow")
+	}
+	if shadow, _ := InShadow(star.Entity, false); shadow {
+		t.Errorf("star in shadow")
+	}
+}
+
+func TestEntityEclipses(t *testing.T) {
+	a, _, star := testOcclusionScene()
+	planetA := S.PlanetInFrame(a)
+	R := planetA.Radius
+	μ := GravitationalConstant * planetA.Mass
+
+	// circular orbit starting on the day side, crossing the shadow half
+	// an orbit later
+	r := 2 * R
+	e := S.NewEntity()
+	S.EntFrames[e] = a
+	S.Orb[e] = StateVectorToOrbital(&V3{-r, 0, 0}, &V3{0, -math.Sqrt(μ / r), 0}, μ)
+
+	period := S.Orb[e].Period()
+	eclipses := EntityEclipses(e, period, false)
+	if len(eclipses) != 1 {
+		t.Fatalf("eclipse count: got %d want 1", len(eclipses))
+	}
+	half := math.Asin(R/r) / twoPi * period
+	exEntry, exExit := period/2-half, period/2+half
+	if math.Abs(eclipses[0].Entry-exEntry) > 2 || math.Abs(eclipses[0].Exit-exExit) > 2 {
+		t.Errorf("got %+v want entry %v exit %v", eclipses[0], exEntry, exExit)
+	}
+
+	// longer with the atmosphere
+	if atm := EntityEclipses(e, period, true); len(atm) != 1 || atm[0].Exit-atm[0].Entry <= eclipses[0].Exit-eclipses[0].Entry {
+		t.Errorf("eclipses with atmosphere %+v", atm)
+	}
+
+	// no planet to be eclipsed by in the star's frame
+	S.EntFrames[e] = S.EntFrames[star.Entity]
+	if len(EntityEclipses(e, period, false)) != 0 {
+		t.Errorf("eclipses without a planet")
+	}
+}
@@ -0,0 +1,352 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CCSDS Orbit Ephemeris Messages (OEM) in the KVN text format, for export
+// of entity trajectories to offline analysis tools.
+//
+// World time 0 maps to the OEM epoch 2000-01-01T12:00:00 UTC.  All ref
+// frames share the axes of the root frame, exported as ICRF.  OEMs are in
+// km and km/s.
+//
+// https://public.ccsds.org/Pubs/502x0b3e1.pdf
+
+const (
+	oemVersion    = "2.0"
+	oemOriginator = "TESSERACT"
+	oemRefFrame   = "ICRF"
+	oemTimeSystem = "UTC"
+	oemTimeLayout = "2006-01-02T15:04:05.000000"
+)
+
+var oemEpoch = time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// OEM is an orbit ephemeris message.
+type OEM struct {
+	Version    string
+	Created    time.Time
+	Originator string
+	Segments   []*OEMSegment
+}
+
+// OEMSegment is the trajectory of one object in one ref frame.  Start and
+// Stop are world times.
+type OEMSegment struct {
+	ObjectName string
+	ObjectId   string
+	CenterName string
+	RefFrame   string
+	TimeSystem string
+	Start      float64
+	Stop       float64
+
+	States []*OEMState
+}
+
+// OEMState is the position (m) and velocity (m/s) of the object at world
+// time Time.
+type OEMState struct {
+	Time float64
+	Pos  *V3
+	Vel  *V3
+}
+
+// EntityOEM returns the trajectory of the entity from world time start to
+// stop, every step seconds, relative the origin of ref frame rf.  The
+// trajectory is propagated from the current state of the entity, without
+// future actions or forces.
+func EntityOEM(e Id, rf *RefFrame, start, stop, step float64) (*OEM, error) {
+	if S.EntFrames[e] == nil {
+		return nil, fmt.Errorf("unknown entity %v", e)
+	}
+	if rf == nil {
+		return nil, errors.New("nil ref frame")
+	}
+	if !(step > 0) || !(stop >= start) || math.IsInf(stop, 0) || math.IsInf(start, 0) {
+		return nil, fmt.Errorf("invalid time span %v to %v step %v", start, stop, step)
+	}
+	n := math.Floor((stop - start) / step)
+	if n+2 > oemMaxStates {
+		return nil, fmt.Errorf("time span %v to %v step %v exceeds %v states", start, stop, step, oemMaxStates)
+	}
+
+	seg := &OEMSegment{
+		ObjectName: fmt.Sprintf("ENTITY %v", e),
+		ObjectId:   strconv.FormatUint(uint64(e), 10),
+		CenterName: frameCenterName(rf),
+		RefFrame:   oemRefFrame,
+		TimeSystem: oemTimeSystem,
+		Start:      start,
+		Stop:       stop,
+		States:     make([]*OEMState, 0, int(n)+2),
+	}
+	add := func(t float64) {
+		pos, vel := entityStateIn(e, rf, t-S.WorldTime)
+		seg.States = append(seg.States, &OEMState{t, pos, vel})
+	}
+	for i := 0.0; i <= n; i++ {
+		add(start + i*step)
+	}
+	if last := start + n*step; last < stop {
+		add(stop)
+	}
+
+	return &OEM{
+		Version:    oemVersion,
+		Created:    time.Now().UTC(),
+		Originator: oemOriginator,
+		Segments:   []*OEMSegment{seg},
+	}, nil
+}
+
+// WriteOEM writes the trajectory of the entity from world time start to
+// stop, every step seconds, relative the origin of ref frame rf to w.  See
+// EntityOEM.
+func WriteOEM(w io.Writer, e Id, rf *RefFrame, start, stop, step float64) error {
+	oem, err := EntityOEM(e, rf, start, stop, step)
+	if err != nil {
+		return err
+	}
+	return oem.Write(w)
+}
+
+// frameCenterName returns the OEM center name of the origin of ref frame
+// rf.
+func frameCenterName(rf *RefFrame) string {
+	if rf.IsRoot() {
+		return "GALAXY"
+	}
+	if star := S.StarInFrame(rf); star != nil {
+		return star.Body.Name
+	}
+	for e, p := range S.Planets {
+		if S.EntFrames[e] == rf {
+			return fmt.Sprintf("PLANET %v", p.Entity)
+		}
+	}
+	return "FRAME"
+}
+
+// Write writes the OEM in KVN format to w.
+func (oem *OEM) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "CCSDS_OEM_VERS = %s\n", oem.Version)
+	fmt.Fprintf(bw, "CREATION_DATE = %s\n", oem.Created.Format(oemTimeLayout))
+	fmt.Fprintf(bw, "ORIGINATOR = %s\n", oem.Originator)
+
+	for _, seg := range oem.Segments {
+		fmt.Fprintf(bw, "\nMETA_START\n")
+		fmt.Fprintf(bw, "OBJECT_NAME = %s\n", seg.ObjectName)
+		fmt.Fprintf(bw, "OBJECT_ID = %s\n", seg.ObjectId)
+		fmt.Fprintf(bw, "CENTER_NAME = %s\n", seg.CenterName)
+		fmt.Fprintf(bw, "REF_FRAME = %s\n", seg.RefFrame)
+		fmt.Fprintf(bw, "TIME_SYSTEM = %s\n", seg.TimeSystem)
+		fmt.Fprintf(bw, "START_TIME = %s\n", oemTime(seg.Start))
+		fmt.Fprintf(bw, "STOP_TIME = %s\n", oemTime(seg.Stop))
+		fmt.Fprintf(bw, "META_STOP\n\n")
+
+		for _, s := range seg.States {
+			fmt.Fprintf(bw, "%s %.6f %.6f %.6f %.9f %.9f %.9f\n", oemTime(s.Time),
+				s.Pos.X/1000, s.Pos.Y/1000, s.Pos.Z/1000,
+				s.Vel.X/1000, s.Vel.Y/1000, s.Vel.Z/1000)
+		}
+	}
+	return bw.Flush()
+}
+
+// oemTime returns the OEM epoch of world time t.
+func oemTime(t float64) string {
+	return oemEpoch.Add(time.Duration(math.Round(t*1e6)) * time.Microsecond).Format(oemTimeLayout)
+}
+
+// parseOEMTime returns the world time of an OEM epoch.
+func parseOEMTime(s string) (float64, error) {
+	t, err := parseOEMEpoch(s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Sub(oemEpoch).Seconds(), nil
+}
+
+// parseOEMEpoch parses an OEM epoch in calendar or day of year format.
+func parseOEMEpoch(s string) (time.Time, error) {
+	s = strings.TrimSuffix(s, "Z")
+	t, err := time.Parse("2006-01-02T15:04:05", s)
+	if err != nil {
+		t, err = time.Parse("2006-002T15:04:05", s)
+	}
+	if err != nil {
+		return t, fmt.Errorf("invalid epoch %q", s)
+	}
+	return t, nil
+}
+
+// ReadOEM reads an OEM in KVN format from r.  Only UTC time is supported;
+// comments, covariance sections and accelerations are skipped.
+func ReadOEM(r io.Reader) (*OEM, error) {
+	oem := new(OEM)
+	var seg *OEMSegment
+	inMeta, inCovariance := false, false
+
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		l := strings.TrimSpace(sc.Text())
+		var err error
+		switch {
+		case l == "" || strings.HasPrefix(l, "COMMENT"):
+		case l == "COVARIANCE_START":
+			inCovariance = true
+		case l == "COVARIANCE_STOP":
+			inCovariance = false
+		case inCovariance:
+		case l == "META_START":
+			if oem.Version == "" {
+				err = errors.New("missing CCSDS_OEM_VERS")
+				break
+			}
+			seg = new(OEMSegment)
+			oem.Segments = append(oem.Segments, seg)
+			inMeta = true
+		case l == "META_STOP":
+			if !inMeta {
+				err = errors.New("META_STOP without META_START")
+				break
+			}
+			inMeta = false
+			err = seg.checkMetadata()
+		case strings.Contains(l, "="):
+			kv := strings.SplitN(l, "=", 2)
+			key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+			switch {
+			case inMeta:
+				err = seg.setMetadata(key, value)
+			case seg == nil:
+				err = oem.setHeader(key, value)
+			default:
+				err = fmt.Errorf("keyword %v outside metadata", key)
+			}
+		default:
+			if seg == nil || inMeta {
+				err = errors.New("ephemeris data outside segment")
+				break
+			}
+			var s *OEMState
+			s, err = parseOEMState(l)
+			if err == nil {
+				seg.States = append(seg.States, s)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("oem line %d: %v", line, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if inMeta {
+		return nil, errors.New("oem: unterminated metadata")
+	}
+	if len(oem.Segments) == 0 {
+		return nil, errors.New("oem: no segments")
+	}
+	return oem, nil
+}
+
+func (oem *OEM) setHeader(key, value string) error {
+	var err error
+	switch key {
+	case "CCSDS_OEM_VERS":
+		oem.Version = value
+	case "CREATION_DATE":
+		oem.Created, err = parseOEMEpoch(value)
+	case "ORIGINATOR":
+		oem.Originator = value
+	default:
+		err = fmt.Errorf("unknown header keyword %v", key)
+	}
+	return err
+}
+
+func (seg *OEMSegment) setMetadata(key, value string) error {
+	var err error
+	switch key {
+	case "OBJECT_NAME":
+		seg.ObjectName = value
+	case "OBJECT_ID":
+		seg.ObjectId = value
+	case "CENTER_NAME":
+		seg.CenterName = value
+	case "REF_FRAME":
+		seg.RefFrame = value
+	case "TIME_SYSTEM":
+		seg.TimeSystem = value
+		if value != oemTimeSystem {
+			err = fmt.Errorf("unsupported time system %v", value)
+		}
+	case "START_TIME":
+		seg.Start, err = parseOEMTime(value)
+	case "STOP_TIME":
+		seg.Stop, err = parseOEMTime(value)
+	}
+	// other keywords, e.g. INTERPOLATION, are optional and ignored
+	return err
+}
+
+func (seg *OEMSegment) checkMetadata() error {
+	if seg.ObjectName == "" || seg.ObjectId == "" || seg.CenterName == "" ||
+		seg.RefFrame == "" || seg.TimeSystem == "" {
+		return errors.New("missing mandatory metadata")
+	}
+	if seg.Stop < seg.Start {
+		return errors.New("STOP_TIME before START_TIME")
+	}
+	return nil
+}
+
+// parseOEMState parses an ephemeris data line: the epoch followed by the
+// position and velocity, and optionally the acceleration.
+func parseOEMState(l string) (*OEMState, error) {
+	fields := strings.Fields(l)
+	if len(fields) != 7 && len(fields) != 10 {
+		return nil, fmt.Errorf("expected 7 or 10 fields, got %v", len(fields))
+	}
+	t, err := parseOEMTime(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	v := make([]float64, 6)
+	for i := range v {
+		v[i], err = strconv.ParseFloat(fields[i+1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", fields[i+1])
+		}
+		v[i] *= 1000 // km to m
+	}
+	return &OEMState{t, &V3{v[0], v[1], v[2]}, &V3{v[3], v[4], v[5]}}, nil
+}
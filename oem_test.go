@@ -0,0 +1,144 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestOEMRoundTrip(t *testing.T) {
+	e := devOrbitingShip()
+	rf := S.EntFrames[e]
+	starRF := &RefFrame{Parent: rootRF}
+	μSun := GravitationalConstant * 1.989e30
+	rf.Parent = starRF
+	rf.Orbit = StateVectorToOrbital(&V3{1.5e11, 0, 0}, &V3{0, math.Sqrt(μSun / 1.5e11), 0}, μSun)
+	S.WorldTime = 100
+
+	start, stop, step := 50.0, 1000.0, 60.0
+	oem, err := EntityOEM(e, starRF, start, stop, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seg := oem.Segments[0]
+	if n := len(seg.States); n != 17 {
+		t.Fatalf("got %v states, expected 17", n)
+	}
+	if first, last := seg.States[0], seg.States[16]; first.Time != start || last.Time != stop {
+		t.Errorf("states from %v to %v, expected %v to %v", first.Time, last.Time, start, stop)
+	}
+
+	// states are relative the origin of the export frame
+	for _, s := range seg.States {
+		pos, vel := S.Orb[e].AtTime(s.Time - S.WorldTime).OrbitalToStateVector()
+		offset, offsetVel := rf.StateIn(starRF, s.Time-S.WorldTime)
+		pos.Add(pos, offset)
+		vel.Add(vel, offsetVel)
+		if new(V3).Sub(pos, s.Pos).Magnitude() > 1e-3 || new(V3).Sub(vel, s.Vel).Magnitude() > 1e-6 {
+			t.Errorf("state at %v: got %v %v, expected %v %v", s.Time, s.Pos.Fmt(), s.Vel.Fmt(), pos.Fmt(), vel.Fmt())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := oem.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadOEM(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Version != oemVersion || read.Originator != oemOriginator ||
+		read.Created.Sub(oem.Created).Seconds() > 1e-6 || len(read.Segments) != 1 {
+		t.Fatalf("unexpected header %+v", read)
+	}
+	rseg := read.Segments[0]
+	if rseg.ObjectId != seg.ObjectId || rseg.ObjectName != seg.ObjectName || rseg.CenterName != seg.CenterName ||
+		rseg.RefFrame != seg.RefFrame || rseg.TimeSystem != seg.TimeSystem ||
+		math.Abs(rseg.Start-start) > 1e-6 || math.Abs(rseg.Stop-stop) > 1e-6 {
+		t.Errorf("unexpected metadata %+v", rseg)
+	}
+	if len(rseg.States) != len(seg.States) {
+		t.Fatalf("read %v states, expected %v", len(rseg.States), len(seg.States))
+	}
+	for i, s := range rseg.States {
+		ex := seg.States[i]
+		if math.Abs(s.Time-ex.Time) > 1e-6 || new(V3).Sub(s.Pos, ex.Pos).Magnitude() > 1 ||
+			new(V3).Sub(s.Vel, ex.Vel).Magnitude() > 1e-3 {
+			t.Errorf("state %v: read %v %v %v, expected %v %v %v", i, s.Time, s.Pos.Fmt(), s.Vel.Fmt(), ex.Time, ex.Pos.Fmt(), ex.Vel.Fmt())
+		}
+	}
+
+	if _, err := EntityOEM(e, starRF, stop, start, step); err == nil {
+		t.Errorf("expected error for reversed time span")
+	}
+	if _, err := EntityOEM(e, starRF, start, stop, 0); err == nil {
+		t.Errorf("expected error for zero step")
+	}
+}
+
+func TestReadOEM(t *testing.T) {
+	valid := `CCSDS_OEM_VERS = 2.0
+COMMENT example
+CREATION_DATE = 2000-001T12:00:00Z
+ORIGINATOR = TEST
+
+META_START
+OBJECT_NAME = PROBE
+OBJECT_ID = 1999-001A
+CENTER_NAME = EARTH
+REF_FRAME = EME2000
+TIME_SYSTEM = UTC
+START_TIME = 2000-01-01T12:00:00
+STOP_TIME = 2000-01-01T12:01:00
+INTERPOLATION = HERMITE
+META_STOP
+
+2000-01-01T12:00:00.000 7000 0 0 0 7.5 0
+2000-01-01T12:01:00 6998.4 450 0 -0.05 7.49 0 0 0 0
+
+COVARIANCE_START
+EPOCH = 2000-01-01T12:00:00
+COVARIANCE_STOP
+`
+	oem, err := ReadOEM(strings.NewReader(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := oem.Segments[0].States
+	if len(states) != 2 || states[1].Time != 60 || states[0].Pos.X != 7e6 || states[1].Vel.Y != 7490 {
+		t.Errorf("unexpected states %+v %+v", states[0], states[1])
+	}
+
+	invalid := map[string]string{
+		"no version":      strings.Replace(valid, "CCSDS_OEM_VERS = 2.0\n", "", 1),
+		"time system":     strings.Replace(valid, "TIME_SYSTEM = UTC", "TIME_SYSTEM = TAI", 1),
+		"missing object":  strings.Replace(valid, "OBJECT_NAME = PROBE\n", "", 1),
+		"short data line": strings.Replace(valid, " 0 7.5 0\n", " 0 7.5\n", 1),
+		"bad number":      strings.Replace(valid, "6998.4", "6998,4", 1),
+		"unterminated":    strings.Split(valid, "META_STOP")[0],
+		"no segments":     strings.Split(valid, "META_START")[0],
+	}
+	for name, msg := range invalid {
+		if _, err := ReadOEM(strings.NewReader(msg)); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
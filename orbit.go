@@ -18,6 +18,7 @@
 package tesseract
 
 import (
+	"encoding/json"
 	"fmt"
 	"math"
 
@@ -64,6 +65,30 @@ func (o *OE) Fmt() string {
 		o.h, RadToDeg(o.i), RadToDeg(o.Ω), o.e, RadToDeg(o.ω), RadToDeg(o.θ), o.μ)
 }
 
+// OEJSON is the JSON encoding of OE.  Angles are in radians.
+type OEJSON struct {
+	H            float64 `json:"h"`
+	I            float64 `json:"i"`
+	RAAN         float64 `json:"raan"`
+	E            float64 `json:"e"`
+	ArgPeriapsis float64 `json:"argPeriapsis"`
+	TrueAnomaly  float64 `json:"trueAnomaly"`
+	Mu           float64 `json:"mu"`
+}
+
+func (o *OE) MarshalJSON() ([]byte, error) {
+	return json.Marshal(OEJSON{o.h, o.i, o.Ω, o.e, o.ω, o.θ, o.μ})
+}
+
+func (o *OE) UnmarshalJSON(b []byte) error {
+	var j OEJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*o = OE{j.H, j.I, j.RAAN, j.E, j.ArgPeriapsis, j.TrueAnomaly, j.Mu}
+	return nil
+}
+
 // SemimajorAxis returns the semimajor axis of the orbit.
 func (o *OE) SemimajorAxis() float64 {
 	h, e, μ := o.h, o.e, o.μ
@@ -176,7 +201,9 @@ func (o *OE) TrueAnomalyFromTime(t float64) float64 {
 	return NormalizeAngle(θ)
 }
 
-// TimeFromTrueAnomaly returns the time for a given true anomaly.
+// TimeFromTrueAnomaly returns the time since periapsis for a given true
+// anomaly.  For parabolic and hyperbolic orbits a true anomaly in (π, 2π)
+// lies before periapsis and the returned time is negative.
 func (o *OE) TimeFromTrueAnomaly(θ float64) float64 {
 	// TODO: normalize / mod
 	if θ < 0 || θ > twoPi {
@@ -192,8 +219,11 @@ func (o *OE) TimeFromTrueAnomaly(θ float64) float64 {
 	case e < 1: // elliptical
 		// Eqn 3.13b
 		x0 := math.Sqrt((1 - e) / (1 + e))
-		x1 := math.Tan(θ) / 2
+		x1 := math.Tan(θ / 2)
 		E := 2 * math.Atan(x0*x1)
+		if E < 0 {
+			E += twoPi
+		}
 		// Eqn 3.14
 		Me := E - e*math.Sin(E)
 		// Eqn 3.15
@@ -201,7 +231,7 @@ func (o *OE) TimeFromTrueAnomaly(θ float64) float64 {
 	case e == 1: // parabolic
 		// Eqn 3.30 (substitution for Mp)
 		x0 := math.Tan(θ / 2)
-		Mp := (1/2)*x0 + (1/6)*math.Pow(x0, 3)
+		Mp := 0.5*x0 + (1.0/6.0)*math.Pow(x0, 3)
 		// Eqn 3.31 (substitution for t)
 		t = (Mp * (h * h * h)) / (μ * μ)
 	case e > 1: // hyperbolic
@@ -219,6 +249,86 @@ func (o *OE) TimeFromTrueAnomaly(θ float64) float64 {
 	return t
 }
 
+// AtTime returns a copy of the orbit with the orbiter moved dt seconds along
+// it from its current true anomaly.  Negative dt moves the orbiter backwards.
+func (o *OE) AtTime(dt float64) *OE {
+	o2 := *o
+	t := o.TimeFromTrueAnomaly(o.θ) + dt
+	if o.e < 1 {
+		period := o.Period()
+		t = math.Mod(t, period)
+		if t < 0 {
+			t += period
+		}
+	}
+	o2.θ = o.TrueAnomalyFromTime(t)
+	return &o2
+}
+
+// TimeToTrueAnomaly returns the time in seconds until the orbiter next
+// reaches true anomaly θ.  Open orbits only reach θ if it lies ahead of
+// the orbiter and within the asymptotes.
+func (o *OE) TimeToTrueAnomaly(θ float64) (float64, error) {
+	θ, err := o.reachable(θ)
+	if err != nil {
+		return 0, err
+	}
+
+	t := o.signedTime(θ) - o.signedTime(o.θ)
+	if t < 0 {
+		if o.e >= 1 {
+			return 0, fmt.Errorf("true anomaly %v already passed", θ)
+		}
+		t += o.Period()
+	}
+	return t, nil
+}
+
+// TimeSinceTrueAnomaly returns the time in seconds since the orbiter last
+// reached true anomaly θ.  Open orbits only reached θ if it lies behind
+// the orbiter and within the asymptotes.
+func (o *OE) TimeSinceTrueAnomaly(θ float64) (float64, error) {
+	θ, err := o.reachable(θ)
+	if err != nil {
+		return 0, err
+	}
+
+	t := o.signedTime(o.θ) - o.signedTime(θ)
+	if t < 0 {
+		if o.e >= 1 {
+			return 0, fmt.Errorf("true anomaly %v not yet reached", θ)
+		}
+		t += o.Period()
+	}
+	return t, nil
+}
+
+// reachable returns θ normalized to [0, 2π), or an error if it lies beyond
+// the asymptotes of an open orbit.
+func (o *OE) reachable(θ float64) (float64, error) {
+	θ = math.Mod(θ, twoPi)
+	if θ < 0 {
+		θ += twoPi
+	}
+	if o.e >= 1 {
+		// Eqn 2.97: true anomaly of the asymptotes of open orbits
+		θinf := math.Acos(-1 / o.e)
+		if θ >= θinf && θ <= twoPi-θinf {
+			return 0, fmt.Errorf("true anomaly %v beyond asymptote %v", θ, θinf)
+		}
+	}
+	return θ, nil
+}
+
+// signedTime returns the time since periapsis at true anomaly θ, negative
+// for the inbound leg of open orbits.
+func (o *OE) signedTime(θ float64) float64 {
+	if o.e < 1 || θ <= math.Pi {
+		return o.TimeFromTrueAnomaly(θ)
+	}
+	return -o.TimeFromTrueAnomaly(twoPi - θ)
+}
+
 func eccentricAnomaly(e, Me float64) float64 {
 	// Algorithm 3.1
 	Ei := Me + e/2
@@ -286,12 +396,33 @@ func StateVectorToOrbital(r, v *V3, μ float64) *OE {
 	eVec := new(V3).MulScalar(x3, 1/μ)
 	e := eVec.Magnitude()
 
-	ω := 0.0
+	if e < DBL_EPSILON {
+		// Circular orbits have no periapsis: ω is zero and θ is measured
+		// from the node line, or from the X axis for equatorial orbits.
+		ref := IHat
+		if nodeLineMag != 0.0 {
+			ref = new(V3).MulScalar(nodeLine, 1/nodeLineMag)
+		}
+		θ := math.Acos(math.Max(-1, math.Min(1, ref.ScalarProduct(r)/dist)))
+		if new(V3).VectorProduct(ref, r).ScalarProduct(h) < 0 {
+			θ = 2*math.Pi - θ
+		}
+		return &OE{hMag, i, Ω, 0, 0, θ, μ}
+	}
+
+	var ω float64
 	if nodeLineMag != 0.0 {
 		ω = math.Acos(nodeLine.ScalarProduct(eVec) / (nodeLineMag * e))
-	}
-	if eVec.Z < 0 {
-		ω = 2*math.Pi - ω
+		if eVec.Z < 0 {
+			ω = 2*math.Pi - ω
+		}
+	} else {
+		// equatorial orbits have no node line: ω is measured from the X
+		// axis, in the direction of motion
+		ω = math.Acos(math.Max(-1, math.Min(1, eVec.X/e)))
+		if (eVec.Y < 0) == (h.Z > 0) {
+			ω = 2*math.Pi - ω
+		}
 	}
 
 	θ := math.Acos(eVec.ScalarProduct(r) / (e * dist))
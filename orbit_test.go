@@ -19,6 +19,7 @@ package tesseract
 
 import (
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/log"
@@ -74,6 +75,20 @@ func TestOrbElemConv(t *testing.T) {
 	// TODO: check o2 vs o
 }
 
+func TestOrbElemConvEquatorial(t *testing.T) {
+	// equatorial orbits have no node line; periapsis and position must
+	// still survive the round trip, for prograde and retrograde orbits
+	for _, vz := range []float64{1, -1} {
+		r := &V3{-3000, 6000, 0}
+		v := &V3{-7, -4 * vz, 0}
+		o := StateVectorToOrbital(r, v, 398600)
+		r2, v2 := o.OrbitalToStateVector()
+		if new(V3).Sub(r, r2).Magnitude() > 1e-6 || new(V3).Sub(v, v2).Magnitude() > 1e-9 {
+			t.Errorf("got r %v v %v, expected r %v v %v", r2.Fmt(), v2.Fmt(), r.Fmt(), v.Fmt())
+		}
+	}
+}
+
 func TestFromTimeElliptic(t *testing.T) {
 	// This test uses intermediate values in Example 3.1 and 3.2.
 	o := &OE{}
@@ -134,6 +149,49 @@ func TestFromTimeHyperbolic(t *testing.T) {
 
 }
 
+func TestTimeFromTrueAnomalyRoundTrip(t *testing.T) {
+	// This test uses the orbit of Example 3.1 and 3.2.
+	// The eccentric anomaly is solved to eccentricAnomalyTolerance, which
+	// bounds the round-trip precision to a few milliseconds.
+	o := &OE{h: 72472, e: 0.37255, μ: 398600.0}
+	for _, tt := range []float64{0, 1000, 5000, 10800, 14000} {
+		θ := o.TrueAnomalyFromTime(tt)
+		if got := o.TimeFromTrueAnomaly(θ); math.Abs(got-tt) > 1e-2 {
+			t.Errorf("t: got %v want %v", got, tt)
+		}
+	}
+}
+
+func TestTimeToTrueAnomaly(t *testing.T) {
+	// This test uses the orbits of Example 3.1 and 3.5.
+	o := &OE{h: 72472, e: 0.37255, μ: 398600.0}
+	o.θ = o.TrueAnomalyFromTime(10800)
+	period := o.Period()
+
+	toPeriapsis, err := o.TimeToTrueAnomaly(0)
+	if err != nil || math.Abs(toPeriapsis-(period-10800)) > 1e-2 {
+		t.Errorf("time to periapsis: got %v %v, expected %v", toPeriapsis, err, period-10800)
+	}
+	toApoapsis, _ := o.TimeToTrueAnomaly(math.Pi)
+	if exp := period/2 + period - 10800; math.Abs(toApoapsis-exp) > 1e-2 {
+		t.Errorf("time to apoapsis: got %v, expected %v", toApoapsis, exp)
+	}
+
+	h := &OE{h: 100170, e: 2.7696, μ: 398600.0}
+	h.θ = h.TrueAnomalyFromTime(4141.4 + 3*3600)
+	if _, err := h.TimeToTrueAnomaly(0); err == nil {
+		t.Errorf("reached passed periapsis of hyperbola")
+	}
+	if _, err := h.TimeToTrueAnomaly(math.Pi); err == nil {
+		t.Errorf("reached true anomaly beyond asymptote")
+	}
+	h.θ = twoPi - 1
+	toPeriapsis, err = h.TimeToTrueAnomaly(0)
+	if exp := h.TimeFromTrueAnomaly(1); err != nil || math.Abs(toPeriapsis-exp) > 1e-6 {
+		t.Errorf("inbound time to periapsis: got %v %v, expected %v", toPeriapsis, err, exp)
+	}
+}
+
 func TestPointsApprox(t *testing.T) {
 	// TODO: support this orbit
 	// Example 4.7.
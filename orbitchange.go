@@ -0,0 +1,296 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Closed-form planners for everyday orbit changes: Hohmann and bi-elliptic
+// transfers, circularization and plane changes.  Burns are impulsive and
+// planned as maneuver nodes, executed with the maneuver action.
+//
+// NOTE: referenced equations, algorithms and examples are from reference
+//       [1] of orbit.go (Curtis).
+
+// OrbitChange is a planned sequence of maneuvers changing an orbit.
+type OrbitChange struct {
+	Nodes  []*ManeuverNode `json:"nodes"`
+	DeltaV float64         `json:"deltaV"` // total of all nodes
+
+	// Predicted orbit after the last burn
+	Orbit *OE `json:"orbit"`
+}
+
+// orbitChange plans an OrbitChange by coasting along the orbit and burning.
+type orbitChange struct {
+	OrbitChange
+	o *OE     // orbit at world time t
+	t float64 // world time
+}
+
+func newOrbitChange(o *OE, now float64) (*orbitChange, error) {
+	if o.e >= 1 {
+		return nil, errors.New("orbit is not closed")
+	}
+	return &orbitChange{OrbitChange: OrbitChange{Nodes: []*ManeuverNode{}}, o: o, t: now}, nil
+}
+
+// coast coasts to true anomaly θ.
+func (c *orbitChange) coast(θ float64) {
+	dt, _ := c.o.TimeToTrueAnomaly(θ) // closed orbits reach all θ
+	c.o = c.o.AtTime(dt)
+	c.t += dt
+}
+
+// burn changes the velocity at the current position to radial speed vr
+// and transverse speed vt in the orbital plane with unit normal n.  The
+// position must lie in that plane.
+func (c *orbitChange) burn(n *V3, vr, vt float64) {
+	pos, vel := c.o.OrbitalToStateVector()
+	rHat := new(V3).Set(pos)
+	rHat.Normalise()
+	transverse := new(V3).VectorProduct(n, rHat)
+
+	dv := new(V3).MulScalar(rHat, vr)
+	dv.AddScaledVector(transverse, vt)
+	dv.Sub(dv, vel)
+
+	c.Nodes = append(c.Nodes, newManeuverNode(c.o, c.t, dv))
+	c.DeltaV += dv.Magnitude()
+	c.o = c.o.AfterImpulse(dv)
+}
+
+// apse burns along the velocity at the current position, an apse, to put
+// the opposite apse at radius r.
+func (c *orbitChange) apse(r float64) {
+	c.burn(c.o.planeNormal(), 0, apseSpeed(c.o.μ, c.o.Altitude(), r))
+}
+
+func (c *orbitChange) result() *OrbitChange {
+	c.Orbit = c.o
+	return &c.OrbitChange
+}
+
+// apseSpeed returns the speed at apse radius r0 of the orbit with
+// opposite apse radius r1.
+func apseSpeed(μ, r0, r1 float64) float64 {
+	// Eqn 6.2
+	h := math.Sqrt(2*μ) * math.Sqrt(r0*r1/(r0+r1))
+	return h / r0
+}
+
+// planeNormal returns the unit normal of the orbital plane, along the
+// angular momentum.
+func (o *OE) planeNormal() *V3 {
+	return inclinedNormal(o.i, o.Ω)
+}
+
+// inclinedNormal returns the unit normal of the orbital plane with
+// inclination i and right ascension of the ascending node Ω.
+func inclinedNormal(i, Ω float64) *V3 {
+	// third row of the transformation matrix of Eqn 4.49
+	return &V3{math.Sin(Ω) * math.Sin(i), -math.Cos(Ω) * math.Sin(i), math.Cos(i)}
+}
+
+// trueAnomalyOf returns the true anomaly of the direction d in the orbital
+// plane.
+func (o *OE) trueAnomalyOf(d *V3) float64 {
+	periapsis := *o
+	periapsis.θ = 0
+	p, _ := periapsis.OrbitalToStateVector()
+	p.Normalise()
+	q := new(V3).VectorProduct(o.planeNormal(), p)
+	return math.Atan2(d.ScalarProduct(q), d.ScalarProduct(p))
+}
+
+// farNode returns the true anomaly of the point, of the two where the
+// orbit crosses the line through the primary along d, farthest from the
+// primary.
+func (o *OE) farNode(d *V3) float64 {
+	θ := o.trueAnomalyOf(d)
+	// Eqn 2.45: the radius is largest where 1 + e*cos(θ) is smallest
+	if math.Cos(θ+math.Pi) < math.Cos(θ) {
+		θ += math.Pi
+	}
+	return θ
+}
+
+// Hohmann plans a Hohmann transfer to the circular orbit of radius r,
+// starting at world time now.  The first burn is at periapsis if raising
+// the orbit and at apoapsis if lowering it, the second half a transfer
+// orbit later.  See Section 6.3.
+func (o *OE) Hohmann(r, now float64) (*OrbitChange, error) {
+	c, err := newOrbitChange(o, now)
+	if err != nil {
+		return nil, err
+	}
+	if r <= 0 {
+		return nil, fmt.Errorf("invalid orbit radius %v", r)
+	}
+	if r >= o.Periapsis() {
+		c.coast(0)
+	} else {
+		c.coast(math.Pi)
+	}
+	c.apse(r)
+	c.coast(c.o.θ + math.Pi)
+	c.apse(r)
+	return c.result(), nil
+}
+
+// BiElliptic plans a bi-elliptic transfer to the circular orbit of radius
+// r through apoapsis radius rB, starting at world time now.  It takes three
+// burns: at periapsis to raise the apoapsis to rB, at rB to move the
+// periapsis to r and at r to circularize.  See Section 6.4.
+func (o *OE) BiElliptic(rB, r, now float64) (*OrbitChange, error) {
+	c, err := newOrbitChange(o, now)
+	if err != nil {
+		return nil, err
+	}
+	if r <= 0 {
+		return nil, fmt.Errorf("invalid orbit radius %v", r)
+	}
+	if rB < r || rB < o.Apoapsis() {
+		return nil, fmt.Errorf("apoapsis %v below target and current orbits", rB)
+	}
+	c.coast(0)
+	c.apse(rB)
+	c.coast(math.Pi)
+	c.apse(r)
+	c.coast(0)
+	c.apse(r)
+	return c.result(), nil
+}
+
+// Circularize plans the burn circularizing the orbit at apoapsis, or at
+// periapsis if apoapsis is false, starting at world time now.
+func (o *OE) Circularize(apoapsis bool, now float64) (*OrbitChange, error) {
+	c, err := newOrbitChange(o, now)
+	if err != nil {
+		return nil, err
+	}
+	if apoapsis {
+		c.coast(math.Pi)
+	} else {
+		c.coast(0)
+	}
+	c.apse(c.o.Altitude())
+	return c.result(), nil
+}
+
+// PlaneChange plans the burn changing the inclination of the orbit by Δi,
+// keeping its shape and line of nodes, starting at world time now.  The
+// burn is at the node farthest from the primary, where the speed and
+// thereby the Δv is the smallest.  See Section 6.9.
+func (o *OE) PlaneChange(Δi, now float64) (*OrbitChange, error) {
+	c, err := newOrbitChange(o, now)
+	if err != nil {
+		return nil, err
+	}
+	i := o.i + Δi
+	if i < 0 || i > math.Pi {
+		return nil, fmt.Errorf("invalid inclination %v", i)
+	}
+	c.coast(o.farNode(&V3{math.Cos(o.Ω), math.Sin(o.Ω), 0}))
+	c.rotate(inclinedNormal(i, o.Ω))
+	return c.result(), nil
+}
+
+// CombinedPlaneChange plans a Hohmann transfer to the circular orbit of
+// radius r, changing the inclination of the orbit by Δi in the second burn,
+// starting at world time now.  Combining the plane change with the speed
+// change costs less than two separate burns (Section 6.9).  The first burn is
+// at a node so that the second is at the opposite node: the lower node if
+// raising the orbit and the higher one if lowering it.
+func (o *OE) CombinedPlaneChange(r, Δi, now float64) (*OrbitChange, error) {
+	c, err := newOrbitChange(o, now)
+	if err != nil {
+		return nil, err
+	}
+	if r <= 0 {
+		return nil, fmt.Errorf("invalid orbit radius %v", r)
+	}
+	i := o.i + Δi
+	if i < 0 || i > math.Pi {
+		return nil, fmt.Errorf("invalid inclination %v", i)
+	}
+
+	θ := o.farNode(&V3{math.Cos(o.Ω), math.Sin(o.Ω), 0})
+	if r >= o.SemimajorAxis() {
+		θ += math.Pi
+	}
+	c.coast(θ)
+	// a purely transverse burn makes the node an apse of the transfer orbit
+	c.apse(r)
+	c.coast(c.o.θ + math.Pi)
+	c.burn(inclinedNormal(i, o.Ω), 0, math.Sqrt(o.μ/r))
+	return c.result(), nil
+}
+
+// MatchPlane plans the burn rotating the orbital plane into the plane of
+// the target orbit, around the same primary, starting at world time now.
+// The burn is at the intersection of the planes farthest from the primary.
+// No burn is planned if the planes already match.
+func (o *OE) MatchPlane(target *OE, now float64) (*OrbitChange, error) {
+	c, err := newOrbitChange(o, now)
+	if err != nil {
+		return nil, err
+	}
+	n := target.planeNormal()
+	line := new(V3).VectorProduct(o.planeNormal(), n)
+	if line.Magnitude() < orbitChangePlaneTolerance {
+		return c.result(), nil
+	}
+	c.coast(o.farNode(line))
+	c.rotate(n)
+	return c.result(), nil
+}
+
+// rotate burns to rotate the orbital plane into the plane with unit normal
+// n, keeping the speed.  The current position must lie in both planes.
+func (c *orbitChange) rotate(n *V3) {
+	pos, vel := c.o.OrbitalToStateVector()
+	r := pos.Magnitude()
+	vr := vel.ScalarProduct(pos) / r
+	c.burn(n, vr, c.o.h/r)
+}
+
+// EntityHohmann plans a Hohmann transfer of the entity to the circular
+// orbit at altitude above the surface of the planet it orbits.
+func EntityHohmann(e Id, altitude float64) (*OrbitChange, error) {
+	planet := S.PlanetInFrame(S.EntFrames[e])
+	if S.Orb[e] == nil || planet == nil {
+		return nil, fmt.Errorf("entity %v is not orbiting a planet", e)
+	}
+	return S.Orb[e].Hohmann(planet.Radius+altitude, S.WorldTime)
+}
+
+// EntityMatchPlane plans the burn rotating the orbital plane of the entity
+// into the orbital plane of the target entity.
+func EntityMatchPlane(e, target Id) (*OrbitChange, error) {
+	if S.Orb[e] == nil || S.Orb[target] == nil {
+		return nil, errors.New("both entities must be in orbit")
+	}
+	if S.EntFrames[e] != S.EntFrames[target] {
+		return nil, errors.New("entities orbit different primaries")
+	}
+	return S.Orb[e].MatchPlane(S.Orb[target], S.WorldTime)
+}
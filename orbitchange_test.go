@@ -0,0 +1,252 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+// flyOrbitChange executes the nodes of c on orbit o at world time now,
+// returning the orbit after the last burn.
+func flyOrbitChange(o *OE, now float64, c *OrbitChange) *OE {
+	for _, n := range c.Nodes {
+		o = o.AtTime(n.At - now)
+		now = n.At
+		o = o.AfterImpulse(o.ManeuverDeltaV(&V3{n.Prograde, n.Normal, n.Radial}))
+	}
+	return o
+}
+
+// checkOrbitChange checks that the planned orbit and the orbit flown by
+// executing the nodes are circular with radius r and inclination i.  The
+// flown orbit accumulates the error of propagating the orbit between nodes.
+func checkOrbitChange(t *testing.T, o *OE, now float64, c *OrbitChange, r, i float64) {
+	t.Helper()
+	for j, n := range c.Nodes {
+		if n.At < now || (j > 0 && n.At < c.Nodes[j-1].At) {
+			t.Errorf("node %v at %v out of order", j, n.At)
+		}
+	}
+	for _, f := range []*OE{c.Orbit, flyOrbitChange(o, now, c)} {
+		if f.e > 1e-4 {
+			t.Errorf("final orbit eccentricity %v, expected circular", f.e)
+		}
+		if math.Abs(f.Altitude()-r) > 1e-5*r {
+			t.Errorf("final orbit radius %v, expected %v", f.Altitude(), r)
+		}
+		if math.Abs(f.i-i) > 1e-6 {
+			t.Errorf("final orbit inclination %v, expected %v", f.i, i)
+		}
+	}
+}
+
+func TestHohmann(t *testing.T) {
+	// This test uses values in Example 6.1.
+	μ, re := 398600.0, 6378.0
+	rp, ra := re+480, re+800
+	o := StateVectorToOrbital(&V3{rp, 0, 0}, &V3{0, apseSpeed(μ, rp, ra), 0}, μ)
+	o = o.AtTime(1000)
+
+	r := re + 16000
+	c, err := o.Hohmann(r, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Nodes) != 2 {
+		t.Fatalf("got %v nodes, expected 2", len(c.Nodes))
+	}
+	for j, ex := range []float64{1.7225, 1.3297} {
+		if n := c.Nodes[j]; math.Abs(n.Prograde-ex) > 1e-4 || n.DeltaV()-n.Prograde > 1e-9 {
+			t.Errorf("burn %v: got %v %v %v, expected prograde %v", j, n.Prograde, n.Normal, n.Radial, ex)
+		}
+	}
+	// first burn at periapsis, second half the transfer orbit later
+	if ex := 100 + o.Period() - 1000; math.Abs(c.Nodes[0].At-ex) > 1e-3 {
+		t.Errorf("first burn at %v, expected %v", c.Nodes[0].At, ex)
+	}
+	transfer := StateVectorToOrbital(&V3{rp, 0, 0}, &V3{0, apseSpeed(μ, rp, r), 0}, μ)
+	if dt := c.Nodes[1].At - c.Nodes[0].At; math.Abs(dt-transfer.Period()/2) > 1e-3 {
+		t.Errorf("transfer time %v, expected %v", dt, transfer.Period()/2)
+	}
+	checkOrbitChange(t, o, 100, c, r, 0)
+
+	// lowering burns retrograde at apoapsis
+	c, err = o.Hohmann(re+300, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Nodes[0].Prograde >= 0 || c.Nodes[1].Prograde >= 0 {
+		t.Errorf("expected retrograde burns, got %v %v", c.Nodes[0].Prograde, c.Nodes[1].Prograde)
+	}
+	checkOrbitChange(t, o, 100, c, re+300, 0)
+}
+
+func TestBiElliptic(t *testing.T) {
+	// This test uses values in Example 6.3.
+	μ := 398600.0
+	rA, rB, rC := 7000.0, 210000.0, 105000.0
+	o := StateVectorToOrbital(&V3{rA, 0, 0}, &V3{0, math.Sqrt(μ / rA), 0}, μ)
+
+	hohmann, err := o.Hohmann(rC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bi, err := o.BiElliptic(rB, rC, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(hohmann.DeltaV-4.0463) > 1e-4 {
+		t.Errorf("hohmann Δv %v, expected 4.0463", hohmann.DeltaV)
+	}
+	if math.Abs(bi.DeltaV-4.0285) > 1e-4 {
+		t.Errorf("bi-elliptic Δv %v, expected 4.0285", bi.DeltaV)
+	}
+	if len(bi.Nodes) != 3 {
+		t.Fatalf("got %v nodes, expected 3", len(bi.Nodes))
+	}
+	checkOrbitChange(t, o, 0, bi, rC, 0)
+
+	if _, err := o.BiElliptic(rC/2, rC, 0); err == nil {
+		t.Errorf("expected error for apoapsis below target orbit")
+	}
+}
+
+func TestCircularize(t *testing.T) {
+	μ, rp, ra := 398600.0, 7000.0, 9000.0
+	o := StateVectorToOrbital(&V3{rp, 0, 0}, &V3{0, apseSpeed(μ, rp, ra), 0}, μ)
+
+	for _, apoapsis := range []bool{true, false} {
+		c, err := o.Circularize(apoapsis, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, other := ra, rp
+		if !apoapsis {
+			r, other = rp, ra
+		}
+		ex := math.Sqrt(μ/r) - apseSpeed(μ, r, other)
+		if len(c.Nodes) != 1 || math.Abs(c.Nodes[0].Prograde-ex) > 1e-9 {
+			t.Errorf("apoapsis %v: got %v, expected prograde %v", apoapsis, c.Nodes, ex)
+		}
+		checkOrbitChange(t, o, 0, c, r, 0)
+	}
+}
+
+func TestPlaneChange(t *testing.T) {
+	μ := 398600.0
+	o := &OE{h: 58000, i: 0.5, Ω: 1, e: 0.3, ω: 2, θ: 0.5, μ: μ}
+	Δi := 10 * math.Pi / 180
+
+	c, err := o.PlaneChange(Δi, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Nodes) != 1 {
+		t.Fatalf("got %v nodes, expected 1", len(c.Nodes))
+	}
+
+	// the burn at the node rotates the transverse velocity by Δi
+	atNode := o.AtTime(c.Nodes[0].At)
+	if ex := 2 * atNode.h / atNode.Altitude() * math.Sin(Δi/2); math.Abs(c.DeltaV-ex) > 1e-9 {
+		t.Errorf("Δv %v, expected %v", c.DeltaV, ex)
+	}
+	// ... at the node farthest from the primary
+	if u := math.Mod(atNode.ω+atNode.θ, math.Pi); math.Abs(u) > 1e-6 && math.Abs(u-math.Pi) > 1e-6 {
+		t.Errorf("burn at argument of latitude %v, expected a node", atNode.ω+atNode.θ)
+	}
+	opposite := *atNode
+	opposite.θ += math.Pi
+	if atNode.Altitude() < opposite.Altitude() {
+		t.Errorf("burn at the near node")
+	}
+
+	for _, f := range []*OE{c.Orbit, flyOrbitChange(o, 0, c)} {
+		if math.Abs(f.i-(o.i+Δi)) > 1e-9 || math.Abs(f.Ω-o.Ω) > 1e-9 ||
+			math.Abs(f.e-o.e) > 1e-9 || math.Abs(f.h-o.h) > 1e-6 {
+			t.Errorf("final orbit %v, expected inclination %v", f.Fmt(), o.i+Δi)
+		}
+	}
+
+	if _, err := o.PlaneChange(-1, 0); err == nil {
+		t.Errorf("expected error for negative inclination")
+	}
+}
+
+func TestCombinedPlaneChange(t *testing.T) {
+	μ, r1, r2 := 398600.0, 6678.0, 42164.0
+	i := 28 * math.Pi / 180
+	o := &OE{h: math.Sqrt(μ * r1), i: i, Ω: 0.3, θ: 1, μ: μ}
+
+	c, err := o.CombinedPlaneChange(r2, -i, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Nodes) != 2 {
+		t.Fatalf("got %v nodes, expected 2", len(c.Nodes))
+	}
+	checkOrbitChange(t, o, 0, c, r2, 0)
+
+	// the second burn changes both speed and direction
+	v1, v2 := apseSpeed(μ, r2, r1), math.Sqrt(μ/r2)
+	ex := math.Sqrt(v1*v1 + v2*v2 - 2*v1*v2*math.Cos(i))
+	if dv := c.Nodes[1].DeltaV(); math.Abs(dv-ex) > 1e-6 {
+		t.Errorf("second burn Δv %v, expected %v", dv, ex)
+	}
+
+	// cheaper than a Hohmann transfer followed by a plane change
+	h, err := o.Hohmann(r2, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := h.Orbit.PlaneChange(-i, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.DeltaV >= h.DeltaV+p.DeltaV {
+		t.Errorf("combined Δv %v not below separate %v", c.DeltaV, h.DeltaV+p.DeltaV)
+	}
+}
+
+func TestMatchPlane(t *testing.T) {
+	μ := 398600.0
+	o := &OE{h: 55000, i: 0.4, Ω: 0.5, e: 0.1, ω: 1, θ: 2, μ: μ}
+	target := &OE{h: 70000, i: 0.6, Ω: 1.2, e: 0.2, ω: 0.3, θ: 0, μ: μ}
+
+	c, err := o.MatchPlane(target, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Nodes) != 1 {
+		t.Fatalf("got %v nodes, expected 1", len(c.Nodes))
+	}
+	n := target.planeNormal()
+	for _, f := range []*OE{c.Orbit, flyOrbitChange(o, 0, c)} {
+		if d := new(V3).Sub(f.planeNormal(), n).Magnitude(); d > 1e-9 {
+			t.Errorf("final plane normal %v, expected %v", f.planeNormal().Fmt(), n.Fmt())
+		}
+		if math.Abs(f.h-o.h) > 1e-6 {
+			t.Errorf("final angular momentum %v, expected %v", f.h, o.h)
+		}
+	}
+
+	c, err = c.Orbit.MatchPlane(target, 0)
+	if err != nil || len(c.Nodes) != 0 {
+		t.Errorf("expected no burn for matching planes, got %v %v", c, err)
+	}
+}
@@ -0,0 +1,219 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Orbital events: apsis passages, node crossings, atmosphere entry and
+// sphere of influence (SOI) exit.  Timers can be scheduled for the next
+// occurrence of an event, and the engine posts events on the message bus
+// as they occur.
+
+const (
+	EventPeriapsis      = "periapsis"
+	EventApoapsis       = "apoapsis"
+	EventAscendingNode  = "ascending node"
+	EventDescendingNode = "descending node"
+	EventAtmosphere     = "atmosphere"
+	EventSOIExit        = "soi exit"
+)
+
+var orbitalEvents = []string{
+	EventPeriapsis,
+	EventApoapsis,
+	EventAscendingNode,
+	EventDescendingNode,
+	EventAtmosphere,
+	EventSOIExit,
+}
+
+// OrbitalEvent is posted on the message bus when an orbital event of an
+// entity occurs.
+type OrbitalEvent struct {
+	Entity Id      `json:"entity"`
+	Event  string  `json:"event"`
+	Time   float64 `json:"time"` // world time
+}
+
+// TimeToPeriapsis returns the time in seconds until the next periapsis
+// passage.
+func (o *OE) TimeToPeriapsis() (float64, error) {
+	return o.timeToEvent(EventPeriapsis, 0)
+}
+
+// TimeToApoapsis returns the time in seconds until the next apoapsis
+// passage.  Open orbits have no apoapsis.
+func (o *OE) TimeToApoapsis() (float64, error) {
+	return o.timeToEvent(EventApoapsis, 0)
+}
+
+// TimeToAscendingNode returns the time in seconds until the orbiter next
+// crosses the reference plane northbound.  Equatorial orbits have no
+// nodes.
+func (o *OE) TimeToAscendingNode() (float64, error) {
+	return o.timeToEvent(EventAscendingNode, 0)
+}
+
+// TimeToDescendingNode returns the time in seconds until the orbiter next
+// crosses the reference plane southbound.  Equatorial orbits have no
+// nodes.
+func (o *OE) TimeToDescendingNode() (float64, error) {
+	return o.timeToEvent(EventDescendingNode, 0)
+}
+
+// TimeToRadius returns the time in seconds until the orbiter next crosses
+// the distance r from the primary, moving away from the primary if
+// outbound and towards it otherwise.
+func (o *OE) TimeToRadius(r float64, outbound bool) (float64, error) {
+	θ, err := o.radiusAnomaly(r, outbound)
+	if err != nil {
+		return 0, err
+	}
+	return o.TimeToTrueAnomaly(θ)
+}
+
+func (o *OE) timeToEvent(event string, r float64) (float64, error) {
+	θ, err := o.eventAnomaly(event, r)
+	if err != nil {
+		return 0, err
+	}
+	return o.TimeToTrueAnomaly(θ)
+}
+
+// eventAnomaly returns the true anomaly of the orbital event.  r is the
+// radius of the atmosphere or SOI for those events.
+func (o *OE) eventAnomaly(event string, r float64) (float64, error) {
+	switch event {
+	case EventPeriapsis:
+		return 0, nil
+	case EventApoapsis:
+		if o.e >= 1 {
+			return 0, errors.New("open orbit has no apoapsis")
+		}
+		return math.Pi, nil
+	case EventAscendingNode, EventDescendingNode:
+		if math.Abs(math.Sin(o.i)) < orbitEquatorialTolerance {
+			return 0, errors.New("equatorial orbit has no nodes")
+		}
+		// nodes are where the argument of latitude ω + θ is 0 or π
+		if event == EventDescendingNode {
+			return math.Pi - o.ω, nil
+		}
+		return -o.ω, nil
+	case EventAtmosphere:
+		return o.radiusAnomaly(r, false)
+	case EventSOIExit:
+		return o.radiusAnomaly(r, true)
+	}
+	return 0, fmt.Errorf("unknown orbital event %q", event)
+}
+
+// radiusAnomaly returns the true anomaly where the orbit crosses the
+// distance r from the primary, outbound or inbound.
+func (o *OE) radiusAnomaly(r float64, outbound bool) (float64, error) {
+	if o.e == 0 {
+		return 0, fmt.Errorf("circular orbit does not cross radius %v", r)
+	}
+	// Eqn 2.45 solved for θ
+	c := (o.h*o.h/(o.μ*r) - 1) / o.e
+	if c < -1 || c > 1 {
+		return 0, fmt.Errorf("orbit does not cross radius %v", r)
+	}
+	θ := math.Acos(c)
+	if !outbound {
+		θ = twoPi - θ
+	}
+	return θ, nil
+}
+
+// timeToOrbitalEvent returns the time in seconds until the next orbital
+// event of the entity's orbit.
+func timeToOrbitalEvent(e Id, event string) (float64, error) {
+	o := S.Orb[e]
+	if o == nil {
+		return 0, fmt.Errorf("entity %v is not in orbit", e)
+	}
+	r, err := eventRadius(e, event)
+	if err != nil {
+		return 0, err
+	}
+	return o.timeToEvent(event, r)
+}
+
+// eventRadius returns the radius of the atmosphere or SOI of the primary
+// of the entity, for those events.
+func eventRadius(e Id, event string) (float64, error) {
+	rf := S.EntFrames[e]
+	switch event {
+	case EventAtmosphere:
+		p := S.PlanetInFrame(rf)
+		if p == nil || p.Atmosphere == nil {
+			return 0, fmt.Errorf("entity %v does not orbit a planet with an atmosphere", e)
+		}
+		return p.Radius + p.Atmosphere.Height, nil
+	case EventSOIExit:
+		r := soiRadius(rf)
+		if math.IsInf(r, 1) {
+			return 0, fmt.Errorf("entity %v orbits a primary without sphere of influence", e)
+		}
+		return r, nil
+	}
+	return 0, nil
+}
+
+// soiRadius returns the radius of the sphere of influence of the planet
+// at the origin of ref frame rf, or positive infinity if there is none.
+// https://en.wikipedia.org/wiki/Sphere_of_influence_(astrodynamics)
+func soiRadius(rf *RefFrame) float64 {
+	p := S.PlanetInFrame(rf)
+	if p == nil || rf.Orbit == nil {
+		return math.Inf(1)
+	}
+	M := rf.Orbit.μ / GravitationalConstant
+	return rf.Orbit.SemimajorAxis() * math.Pow(p.Mass/M, 2.0/5.0)
+}
+
+// orbitalEventsSince returns the orbital events of the entity that
+// occurred during the last dt seconds, in no particular order.
+func orbitalEventsSince(e Id, dt float64) []*OrbitalEvent {
+	o := S.Orb[e]
+	events := []*OrbitalEvent{}
+	if o == nil {
+		return events
+	}
+	for _, event := range orbitalEvents {
+		r, err := eventRadius(e, event)
+		if err != nil {
+			continue
+		}
+		θ, err := o.eventAnomaly(event, r)
+		if err != nil {
+			continue
+		}
+		since, err := o.TimeSinceTrueAnomaly(θ)
+		if err != nil || since >= dt {
+			continue
+		}
+		events = append(events, &OrbitalEvent{e, event, S.WorldTime - since})
+	}
+	return events
+}
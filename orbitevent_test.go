@@ -0,0 +1,131 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestTimeToOrbitalEvents(t *testing.T) {
+	// This test uses values in Example 3.1.
+	μ, rp, ra := 398600.0, 9600.0, 21000.0
+	o := StateVectorToOrbital(&V3{rp, 0, 0}, &V3{0, apseSpeed(μ, rp, ra), 0}, μ)
+
+	if dt, err := o.TimeToApoapsis(); err != nil || math.Abs(dt-18834/2) > 1 {
+		t.Errorf("time to apoapsis %v %v, expected %v", dt, err, 18834/2)
+	}
+	if dt, err := o.AtTime(100).TimeToPeriapsis(); err != nil || math.Abs(dt-(o.Period()-100)) > 1e-3 {
+		t.Errorf("time to periapsis %v %v, expected %v", dt, err, o.Period()-100)
+	}
+
+	θ := 120 * math.Pi / 180
+	r := o.h * o.h / μ / (1 + o.e*math.Cos(θ))
+	if dt, err := o.TimeToRadius(r, true); err != nil || math.Abs(dt-4077) > 1 {
+		t.Errorf("time to outbound radius %v %v, expected 4077", dt, err)
+	}
+	if dt, err := o.TimeToRadius(r, false); err != nil || math.Abs(dt-(o.Period()-4077)) > 1 {
+		t.Errorf("time to inbound radius %v %v, expected %v", dt, err, o.Period()-4077)
+	}
+	if _, err := o.TimeToRadius(ra+1, true); err == nil {
+		t.Errorf("expected error for radius beyond apoapsis")
+	}
+	if since, err := o.AtTime(100).TimeSinceTrueAnomaly(0); err != nil || math.Abs(since-100) > 1e-3 {
+		t.Errorf("time since periapsis %v %v, expected 100", since, err)
+	}
+
+	// nodes are crossings of the XY plane
+	inclined := &OE{h: o.h, i: 0.5, Ω: 1, e: o.e, ω: 1, θ: 0, μ: μ}
+	for _, ascending := range []bool{true, false} {
+		dt, err := inclined.TimeToAscendingNode()
+		if !ascending {
+			dt, err = inclined.TimeToDescendingNode()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		pos, vel := inclined.AtTime(dt).OrbitalToStateVector()
+		if math.Abs(pos.Z) > 1e-3 || (vel.Z > 0) != ascending {
+			t.Errorf("ascending %v: node at %v moving %v", ascending, pos.Fmt(), vel.Fmt())
+		}
+	}
+
+	if _, err := o.TimeToAscendingNode(); err == nil {
+		t.Errorf("expected error for nodes of equatorial orbit")
+	}
+	// orbits after burns are equatorial only within rounding errors
+	v := apseSpeed(μ, rp, ra)
+	for _, vz := range []float64{1e-7 * v, -1e-7 * v} {
+		for _, vy := range []float64{v, -v} {
+			near := StateVectorToOrbital(&V3{rp, 0, 0}, &V3{0, vy, vz}, μ)
+			if _, err := near.TimeToDescendingNode(); err == nil {
+				t.Errorf("expected error for nodes of nearly equatorial orbit with inclination %v", near.i)
+			}
+		}
+	}
+	hyperbolic := StateVectorToOrbital(&V3{rp, 0, 0}, &V3{0, 12, 0}, μ)
+	if _, err := hyperbolic.TimeToApoapsis(); err == nil {
+		t.Errorf("expected error for apoapsis of open orbit")
+	}
+}
+
+func TestPostOrbitalEvents(t *testing.T) {
+	ResetState()
+	ge := &GameEngine{}
+	bus := S.MsgBus.Subscribe()
+
+	μ, rp, ra := 398600.0, 9600.0, 21000.0
+	e := S.NewEntity()
+	S.Orb[e] = StateVectorToOrbital(&V3{rp, 0, 0}, &V3{0, apseSpeed(μ, rp, ra), 0}, μ).AtTime(-0.5)
+
+	frame := func() {
+		S.WorldTime += 1
+		ge.coastOrbits(1)
+		ge.postOrbitalEvents(1)
+	}
+
+	frame()
+	select {
+	case msg := <-bus:
+		ev := new(OrbitalEvent)
+		if err := json.Unmarshal(msg, ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Entity != e || ev.Event != EventPeriapsis || math.Abs(ev.Time-0.5) > 1e-6 {
+			t.Errorf("unexpected event %+v", ev)
+		}
+	default:
+		t.Fatal("no periapsis event posted")
+	}
+
+	frame()
+	select {
+	case msg := <-bus:
+		t.Errorf("unexpected event %s", msg)
+	default:
+	}
+
+	// the bus drops messages for subscribers not keeping up
+	for i := 0; i < msgBusBufferSize+1; i++ {
+		S.MsgBus.Post([]byte("{}"))
+	}
+	if len(bus) != msgBusBufferSize {
+		t.Errorf("got %v buffered messages, expected %v", len(bus), msgBusBufferSize)
+	}
+}
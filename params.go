@@ -34,6 +34,10 @@ const (
 	// analytical or precalculated solutions
 	tolerance = 0.000000000000001
 
+	// quaternion dot product distance from 1 below which slerp falls back
+	// to nlerp, avoiding division by a near-zero sine
+	slerpThreshold = 1e-6
+
 	eccentricAnomalyTolerance           = 1e-6
 	hyperbolicEccentricAnomalyTolerance = 1e-6
 
@@ -77,8 +81,70 @@ const (
 	//
 	// Game Engine
 	//
-	loopTarget        = 1000 * time.Millisecond
-	maxActionsPerLoop = 10
+	loopTarget         = 1000 * time.Millisecond
+	maxActionsPerLoop  = 10
+	maxActionDuration  = 24 * 3600 // s
+	auditLogSize       = 1024
+	maxTimersPerEntity = 32
+	msgBusBufferSize   = 64
+
+	// attitude autopilot PD controller natural frequency (rad/s) and
+	// damping ratio.  The frequency must stay well below the engine
+	// loop frequency for the controller to remain stable.
+	attitudeNaturalFreq = 0.2
+	attitudeDamping     = 1.0
+
+	// time (s) allowed for aligning the ship before a maneuver burn, the
+	// alignment (radians) needed to start burning and the time (s) the
+	// last of the burn's Δv is spread over
+	maneuverAlignTime      = 60.0
+	maneuverAlignTolerance = 0.01
+	burnTailTime           = 5.0
+
+	// time (s) past its start a burn waits for the ship to align before
+	// the burn fails
+	burnAlignTimeout = 120.0
+
+	// Lambert solver (Algorithm 5.2) Newton iteration tolerance and
+	// iteration limit, and the max points of a porkchop search
+	lambertTolerance     = 1e-8
+	lambertMaxIterations = 5000
+	porkchopMaxPoints    = 250000
+
+	// sine of the angle below which orbital planes are considered equal
+	orbitChangePlaneTolerance = 1e-9
+
+	// sine of the inclination below which orbits are considered equatorial
+	// and have no nodes; above the resolution of the inclination computed
+	// from a state vector (about 1e-8)
+	orbitEquatorialTolerance = 1e-6
+
+	// orbit sampling, time precision (s) and max periods of eclipse
+	// predictions
+	eclipseSamplesPerOrbit = 360
+	eclipseTimeTolerance   = 1e-3
+	eclipseMaxPeriods      = 100
+
+	// orbit sampling, time precision (s) and max periods of closest
+	// approach predictions
+	approachSamplesPerOrbit = 360
+	approachTimeTolerance   = 1e-3
+	approachMaxPeriods      = 100
+
+	// orbit sampling of ground tracks and the max periods of a track
+	groundTrackSamplesPerOrbit = 180
+	groundTrackMaxPeriods      = 100
+
+	// Newton iteration tolerance and iteration limit of collinear Lagrange
+	// points, in units of the distance between the bodies
+	lagrangeTolerance     = 1e-14
+	lagrangeMaxIterations = 100
+
+	// angle (radians) below which flyby directions are considered parallel
+	flybyTurnTolerance = 1e-9
+
+	// max ephemeris states of an exported OEM
+	oemMaxStates = 100000
 
 	//
 	// Game Design
@@ -88,6 +154,28 @@ const (
 	minStellarProximity   = (1.5 * auly) / gridUnit
 	sectorTraversalFactor = 0.25
 
+	// radius (m) of interstellar ref frames: entities dropping out of
+	// hyperspace within it join the frame
+	interstellarFrameRadius = aum
+
+	// radius (m) of star system ref frames; hyperspace routes crossing a
+	// star system are cut short at it
+	starSystemRadius = 100 * aum
+
+	// hyperdrives cannot engage within hyperdriveWellMultiple times the
+	// SOI, or Hill radius if hyperdriveHillWells, of planets and moons, or
+	// hyperdriveStarWellRadii star radii of stars
+	hyperdriveWellMultiple  = 1.0
+	hyperdriveHillWells     = false
+	hyperdriveStarWellRadii = 100.0
+
+	// range (m) of interdiction fields
+	interdictionRange = 10 * aum
+
+	// distance, in sector sizes, between the points of a planned jump
+	// checked for the sectors it passes through
+	routeSectorStep = 0.25
+
 	maxPlanets = 14
 	maxMoons   = 6
 
@@ -113,6 +201,7 @@ var (
 	//
 	// Math
 	//
+	IHat = &V3{1, 0, 0}
 	KHat = &V3{0, 0, 1}
 
 	massHistogramFile = "data/Galaxy_stellar_mass_histogram.txt"
@@ -61,8 +61,10 @@ func (p *Physics) IsHotPostUpdate(e Id) bool {
 func updateClassicalMechanics(worldTime, elapsed float64, rf *RefFrame, e Id) {
 	var pos, vel *V3
 	if S.Orb[e] != nil {
+		// orbiting entities coast along their orbit during the frame;
+		// forces are applied on top of the coasted state
 		log.Debug("updateClassicalMechanics", "oe", S.Orb[e].Fmt())
-		pos, vel = S.Orb[e].OrbitalToStateVector()
+		pos, vel = S.Orb[e].AtTime(elapsed).OrbitalToStateVector()
 		log.Debug("updateClassicalMechanics", "pos", pos.Fmt(), "vel", vel.Fmt())
 	} else {
 		pos = S.Pos[e]
@@ -90,14 +92,10 @@ func updateClassicalMechanics(worldTime, elapsed float64, rf *RefFrame, e Id) {
 	// TODO: skip updates if resulting linearForce and/or torque is zero.
 	log.Debug("updateClassicalMechanics", "lf", linearForce, "tq", torque)
 
-	if !linearForce.IsZero() {
-		// update linear acceleration from forces
-		inverseMass := float64(1) / *(S.Mass[e])
-		acc := new(V3)
-		acc.AddScaledVector(linearForce, inverseMass)
-		// update linear velocity
-		vel.AddScaledVector(acc, elapsed)
-	}
+	// update linear acceleration from forces
+	inverseMass := float64(1) / *(S.Mass[e])
+	acc := new(V3)
+	acc.AddScaledVector(linearForce, inverseMass)
 
 	if !torque.IsZero() {
 		// update angular acceleration from torques
@@ -106,8 +104,13 @@ func updateClassicalMechanics(worldTime, elapsed float64, rf *RefFrame, e Id) {
 		S.Rot[e].R.AddScaledVector(angularAcc, elapsed)
 	}
 
-	// update linear position
-	pos.AddScaledVector(vel, elapsed)
+	// update linear velocity and position
+	vel.AddScaledVector(acc, elapsed)
+	if S.Orb[e] != nil {
+		pos.AddScaledVector(acc, elapsed*elapsed/2)
+	} else {
+		pos.AddScaledVector(vel, elapsed)
+	}
 
 	// update angular position (orientation)
 	S.Ori[e].AddScaledVector(S.Rot[e].R, elapsed)
@@ -29,6 +29,9 @@ type Planet struct {
 
 	SurfaceGravity float64
 	Atmosphere     *Atmosphere
+
+	// Equatorial surface magnetic field strength in tesla (T)
+	MagField float64
 }
 
 // DefaultOrbit returns a circular, prograde orbit 100km above a planet's
@@ -36,7 +39,7 @@ type Planet struct {
 func (p *Planet) DefaultOrbit() *OE {
 	e, i, Ω, ω, θ := 0.0, 0.0, 0.0, 0.0, 0.0
 	μ := GravitationalConstant * p.Mass
-	r := 100000.0
+	r := p.Radius + 100000.0
 	if p.Atmosphere != nil {
 		r += p.Atmosphere.Height
 	}
@@ -61,9 +64,58 @@ func (p *Planet) GravityAtAltitude(alt float64) float64 {
 	return p.SurfaceGravity * (x * x)
 }
 
-// Where we lock X,Y,Z coords does not matter, as we do not yet have
-// surface features.  Simply select a orientation derived from the
-// planet's orientation 3D vector.
-func (p *Planet) GeodeticToCartesian(lat, lon float64) float64 {
-	return 0
+// Orientation returns the rotation from planet-fixed coordinates to the
+// coordinates of the planet's ref frame at world time t.  The planet's spin
+// axis is tilted AxialTilt degrees from the frame's Z axis, around its X
+// axis, and the planet rotates around it once every RotationPeriod seconds.
+// The prime meridian lies along the X axis at world time 0.
+func (p *Planet) Orientation(t float64) *Q {
+	tilt := new(Q).SetAxisAngle(&V3{1, 0, 0}, DegToRad(p.AxialTilt))
+	spin := new(Q).SetAxisAngle(&V3{0, 0, 1}, p.rotationAngle(t))
+	return tilt.Mul(spin)
+}
+
+// rotationAngle returns the angle in radians the planet has rotated
+// around its spin axis at world time t.
+func (p *Planet) rotationAngle(t float64) float64 {
+	if p.RotationPeriod == 0 {
+		return 0
+	}
+	return math.Mod(twoPi*t/p.RotationPeriod, twoPi)
+}
+
+// FixedPosition returns the planet-fixed coordinates of pos, a position
+// relative the planet in the coordinates of its ref frame, at world time t.
+func (p *Planet) FixedPosition(pos *V3, t float64) *V3 {
+	return new(Q).Conjugate(p.Orientation(t)).Rotate(pos)
+}
+
+// FramePosition is the inverse of FixedPosition: it returns the position
+// relative the planet in the coordinates of its ref frame of the
+// planet-fixed position pos at world time t.
+func (p *Planet) FramePosition(pos *V3, t float64) *V3 {
+	return p.Orientation(t).Rotate(pos)
+}
+
+// CartesianToGeodetic returns the latitude and longitude in radians and the
+// altitude above the surface of the planet-fixed position pos.  Planets are
+// spheres, so geodetic and geocentric latitude are the same.
+func (p *Planet) CartesianToGeodetic(pos *V3) (float64, float64, float64) {
+	r := pos.Magnitude()
+	if r == 0 {
+		return 0, 0, -p.Radius
+	}
+	return math.Asin(pos.Z / r), math.Atan2(pos.Y, pos.X), r - p.Radius
+}
+
+// GeodeticToCartesian returns the planet-fixed position at latitude lat
+// and longitude lon in radians and altitude alt above the surface.  See
+// CartesianToGeodetic.
+func (p *Planet) GeodeticToCartesian(lat, lon, alt float64) *V3 {
+	r := p.Radius + alt
+	return &V3{
+		r * math.Cos(lat) * math.Cos(lon),
+		r * math.Cos(lat) * math.Sin(lon),
+		r * math.Sin(lat),
+	}
 }
@@ -0,0 +1,95 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func testPlanet() *Planet {
+	return &Planet{
+		Mass:           6.4171e23,
+		Radius:         3389.5 * 1000,
+		AxialTilt:      25.19,
+		RotationPeriod: 24.6 * 3600,
+		Atmosphere: &Atmosphere{
+			Height:      40 * 1000,
+			ScaleHeight: 11.1 * 1000,
+		},
+	}
+}
+
+func TestPlanetFixedPosition(t *testing.T) {
+	p := testPlanet()
+	r := p.Radius + 1000
+	tilt := DegToRad(p.AxialTilt)
+
+	checkGeodetic := func(pos *V3, at, exLat, exLon float64) {
+		t.Helper()
+		lat, lon, alt := p.CartesianToGeodetic(p.FixedPosition(pos, at))
+		dLon := 0.0
+		if !math.IsNaN(exLon) { // longitude of the poles is undefined
+			dLon = math.Remainder(lon-exLon, twoPi)
+		}
+		if math.Abs(lat-exLat) > 1e-9 || math.Abs(dLon) > 1e-9 || math.Abs(alt-1000) > 1e-6 {
+			t.Errorf("%v at %v: got %v %v %v, expected %v %v", pos.Fmt(), at, lat, lon, alt, exLat, exLon)
+		}
+	}
+
+	// the prime meridian is along the X axis at world time 0
+	checkGeodetic(&V3{r, 0, 0}, 0, 0, 0)
+	// the north pole is tilted around the X axis
+	checkGeodetic(&V3{0, -r * math.Sin(tilt), r * math.Cos(tilt)}, 0, math.Pi/2, math.NaN())
+	checkGeodetic(&V3{0, 0, r}, 0, math.Pi/2-tilt, math.Pi/2)
+	// the planet rotates east under a fixed position
+	checkGeodetic(&V3{r, 0, 0}, p.RotationPeriod/4, 0, -math.Pi/2)
+	checkGeodetic(&V3{r, 0, 0}, p.RotationPeriod, 0, 0)
+}
+
+func TestGeodeticToCartesian(t *testing.T) {
+	p := testPlanet()
+	for _, c := range [][3]float64{{0, 0, 0}, {0.3, -2, 1000}, {-1.2, 3, 250e3}} {
+		pos := p.GeodeticToCartesian(c[0], c[1], c[2])
+		if math.Abs(pos.Magnitude()-(p.Radius+c[2])) > 1e-6 {
+			t.Errorf("%v: got radius %v, expected %v", c, pos.Magnitude(), p.Radius+c[2])
+		}
+		lat, lon, alt := p.CartesianToGeodetic(pos)
+		if math.Abs(lat-c[0]) > 1e-12 || math.Abs(lon-c[1]) > 1e-12 || math.Abs(alt-c[2]) > 1e-6 {
+			t.Errorf("%v: round trip to %v %v %v", c, lat, lon, alt)
+		}
+
+		at := 12345.0
+		if back := p.FixedPosition(p.FramePosition(pos, at), at); new(V3).Sub(back, pos).Magnitude() > 1e-6 {
+			t.Errorf("%v: frame round trip to %v, expected %v", c, back.Fmt(), pos.Fmt())
+		}
+	}
+}
+
+func TestPlanetDefaultOrbit(t *testing.T) {
+	p := testPlanet()
+	// 100 km above the atmosphere, measured from the planet's center
+	ex := p.Radius + 100e3 + p.Atmosphere.Height
+	if r := p.DefaultOrbit().Altitude(); math.Abs(r-ex) > 1e-6 {
+		t.Errorf("got orbit radius %v, expected %v", r, ex)
+	}
+	p.Atmosphere = nil
+	if r := p.DefaultOrbit().Altitude(); math.Abs(r-(p.Radius+100e3)) > 1e-6 {
+		t.Errorf("got orbit radius %v without atmosphere, expected %v", r, p.Radius+100e3)
+	}
+}
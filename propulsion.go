@@ -0,0 +1,214 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"fmt"
+	"math"
+)
+
+// Engine classes are analogous to ship classes (see ship.go): each engine
+// class is encoded as a set of constants and an empty struct implements
+// the EngineClass interface.  A fitted engine (MainEngine) holds the
+// dynamic engine state and implements the Engine interface.
+//
+// Engines are throttleable within [min, max] thrust, spool up and down
+// over time and can be gimballed off the ship's forward axis, producing
+// torque around the ship's center of mass.
+
+type EngineClass interface {
+	// The mass in kilograms (kg) of the engine module.
+	MassBase() float64
+
+	// Max thrust in newtons (N).
+	MaxThrustBase() float64
+
+	// Min throttle as a fraction (0-1) of max thrust.  The engine cannot
+	// sustain a non-zero thrust below this.
+	MinThrottleBase() float64
+
+	// Max gimbal angle in radians around the ship's X (pitch) and Y (yaw)
+	// axes.
+	GimbalCap() float64
+
+	// Time in seconds (s) to spool from zero to max thrust (and back).
+	SpoolTimeBase() float64
+
+	// Distance in meters (m) from the ship's center of mass to the engine's
+	// gimbal point, along the ship's negative forward axis.
+	LeverBase() float64
+}
+
+// MainEngine is an engine module fitted to a ship.
+type MainEngine struct {
+	Class EngineClass
+
+	thrust, target float64 // N
+	pitch, yaw     float64 // radians
+}
+
+func (m *MainEngine) MaxThrust() float64 {
+	return m.Class.MaxThrustBase()
+}
+
+func (m *MainEngine) MinThrust() float64 {
+	return m.Class.MinThrottleBase() * m.Class.MaxThrustBase()
+}
+
+func (m *MainEngine) Thrust() float64 {
+	return m.thrust
+}
+
+func (m *MainEngine) SetThrust(t float64) error {
+	if math.IsNaN(t) || t < 0 || t > m.MaxThrust() || (t > 0 && t < m.MinThrust()) {
+		return fmt.Errorf("thrust %v outside engine range 0 or %v-%v", t, m.MinThrust(), m.MaxThrust())
+	}
+	m.target = t
+	return nil
+}
+
+func (m *MainEngine) SetGimbal(pitch, yaw float64) error {
+	max := m.Class.GimbalCap()
+	if math.IsNaN(pitch) || math.IsNaN(yaw) || math.Abs(pitch) > max || math.Abs(yaw) > max {
+		return fmt.Errorf("gimbal %v %v outside engine range ±%v", pitch, yaw, max)
+	}
+	m.pitch, m.yaw = pitch, yaw
+	return nil
+}
+
+// Update spools the engine towards its target thrust for elapsed seconds
+// and returns the average force and torque, in body space, over that time.
+func (m *MainEngine) Update(elapsed float64) (*V3, *V3) {
+	if elapsed <= 0 {
+		return nil, nil
+	}
+	t0 := m.thrust
+	rate := m.MaxThrust() / m.Class.SpoolTimeBase()
+	step := rate * elapsed
+
+	// average thrust over elapsed, including the part spent at target
+	var avg float64
+	switch d := m.target - t0; {
+	case math.Abs(d) <= step:
+		spool := math.Abs(d) / rate
+		avg = ((t0+m.target)/2*spool + m.target*(elapsed-spool)) / elapsed
+		m.thrust = m.target
+	default:
+		m.thrust = t0 + math.Copysign(step, d)
+		avg = (t0 + m.thrust) / 2
+	}
+
+	if avg == 0 {
+		return nil, nil
+	}
+
+	// thrust direction: forward (+Z) rotated by the gimbal angles
+	dir := new(Q).SetEuler(m.pitch, m.yaw, 0).Rotate(&V3{0, 0, 1})
+	force := dir.MulScalar(dir, avg)
+	lever := &V3{0, 0, -m.Class.LeverBase()}
+	torque := new(V3).VectorProduct(lever, force)
+	return force, torque
+}
+
+// FitEngine fits an engine module of the engine class to the ship,
+// replacing any fitted engine, and updates the ship's mass.
+func FitEngine(e Id, class EngineClass) {
+	m := *S.Mass[e]
+	if old, ok := S.Engine[e].(*MainEngine); ok {
+		m -= old.Class.MassBase()
+	}
+	S.Engine[e] = &MainEngine{Class: class}
+	SetShipMass(e, m+class.MassBase())
+}
+
+// EngineForceGen applies the force and torque of a ship's fitted engine.
+// Once its burn time is up the engine is throttled to zero; the force
+// generator expires when the engine has spooled down.
+type EngineForceGen struct {
+	timeLeft float64
+	expired  bool
+}
+
+func (g *EngineForceGen) UpdateForce(e Id, elapsed float64) (*V3, *V3) {
+	engine := S.Engine[e]
+	if engine == nil {
+		g.expired = true
+		return nil, nil
+	}
+
+	g.timeLeft = math.Max(0, g.timeLeft-elapsed)
+	if g.timeLeft == 0 {
+		engine.SetThrust(0)
+	}
+
+	force, torque := engine.Update(elapsed)
+	g.expired = g.timeLeft == 0 && engine.Thrust() == 0
+	if force == nil {
+		return nil, nil
+	}
+	q := S.Ori[e]
+	return q.Rotate(force), q.Rotate(torque)
+}
+
+func (g *EngineForceGen) IsExpired() bool {
+	return g.expired
+}
+
+//
+// Engine Classes
+//
+
+// Kestrel is a light engine with a wide throttle range, fast spool and
+// generous gimbal; suited for agile ships.
+type Kestrel struct{}
+
+const (
+	massBaseKestrel        = 2000     // kg
+	maxThrustBaseKestrel   = 600000.0 // N
+	minThrottleBaseKestrel = 0.1      // fraction of max thrust
+	gimbalCapKestrel       = 0.1      // radians
+	spoolTimeBaseKestrel   = 2.0      // s
+	leverBaseKestrel       = 12.0     // m
+)
+
+func (k *Kestrel) MassBase() float64        { return massBaseKestrel }
+func (k *Kestrel) MaxThrustBase() float64   { return maxThrustBaseKestrel }
+func (k *Kestrel) MinThrottleBase() float64 { return minThrottleBaseKestrel }
+func (k *Kestrel) GimbalCap() float64       { return gimbalCapKestrel }
+func (k *Kestrel) SpoolTimeBase() float64   { return spoolTimeBaseKestrel }
+func (k *Kestrel) LeverBase() float64       { return leverBaseKestrel }
+
+// Auroch is a heavy engine with high thrust, a narrow throttle range,
+// slow spool and little gimbal; suited for haulers.
+type Auroch struct{}
+
+const (
+	massBaseAuroch        = 9000      // kg
+	maxThrustBaseAuroch   = 2400000.0 // N
+	minThrottleBaseAuroch = 0.6       // fraction of max thrust
+	gimbalCapAuroch       = 0.03      // radians
+	spoolTimeBaseAuroch   = 8.0       // s
+	leverBaseAuroch       = 15.0      // m
+)
+
+func (a *Auroch) MassBase() float64        { return massBaseAuroch }
+func (a *Auroch) MaxThrustBase() float64   { return maxThrustBaseAuroch }
+func (a *Auroch) MinThrottleBase() float64 { return minThrottleBaseAuroch }
+func (a *Auroch) GimbalCap() float64       { return gimbalCapAuroch }
+func (a *Auroch) SpoolTimeBase() float64   { return spoolTimeBaseAuroch }
+func (a *Auroch) LeverBase() float64       { return leverBaseAuroch }
@@ -0,0 +1,140 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEngineThrottleRange(t *testing.T) {
+	e := devOrbitingShip()
+	FitEngine(e, &Auroch{})
+
+	min := minThrottleBaseAuroch * maxThrustBaseAuroch
+	for _, thrust := range []float64{-1, min / 2, maxThrustBaseAuroch * 1.01, math.NaN()} {
+		if err := (&ActionEngineThrust{e, thrust, 10}).Execute(); err == nil {
+			t.Errorf("expected error for thrust %v", thrust)
+		}
+	}
+	if err := (&ActionEngineThrust{e, min, 10}).Execute(); err != nil {
+		t.Error(err)
+	}
+	if err := (&ActionEngineGimbal{e, gimbalCapAuroch * 2, 0}).Execute(); err == nil {
+		t.Errorf("expected gimbal range error")
+	}
+}
+
+func TestEngineSpool(t *testing.T) {
+	m := &MainEngine{Class: &Kestrel{}}
+	if err := m.SetThrust(maxThrustBaseKestrel); err != nil {
+		t.Fatal(err)
+	}
+
+	// half way through spool up after one second, averaging a quarter
+	f, torque := m.Update(1)
+	if m.Thrust() != maxThrustBaseKestrel/2 || f.Z != maxThrustBaseKestrel/4 {
+		t.Errorf("got thrust %v force %v", m.Thrust(), f.Z)
+	}
+	if torque != nil && !torque.IsZero() {
+		t.Errorf("ungimballed engine produced torque %v", torque)
+	}
+
+	// reaches max thrust and stays there for the rest of the update
+	f, _ = m.Update(2)
+	exAvg := (0.75*maxThrustBaseKestrel*1 + maxThrustBaseKestrel*1) / 2
+	if m.Thrust() != maxThrustBaseKestrel || math.Abs(f.Z-exAvg) > 1e-6 {
+		t.Errorf("got thrust %v force %v want %v", m.Thrust(), f.Z, exAvg)
+	}
+
+	// gimballed thrust produces torque around the center of mass
+	m.SetGimbal(gimbalCapKestrel, 0)
+	_, torque = m.Update(1)
+	if torque == nil || torque.X == 0 {
+		t.Errorf("gimballed engine produced no pitch torque: %v", torque)
+	}
+}
+
+func TestEngineBuildsDiffer(t *testing.T) {
+	burn := func(class EngineClass) float64 {
+		e := devOrbitingShip()
+		FitEngine(e, class)
+		_, v0 := S.EntityState(e, 0)
+		if err := (&ActionEngineThrust{e, class.MaxThrustBase(), 10}).Execute(); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			updateClassicalMechanics(float64(i), 1.0, S.EntFrames[e], e)
+		}
+		_, v1 := S.EntityState(e, 0)
+		return new(V3).Sub(v1, v0).Magnitude()
+	}
+
+	light, heavy := burn(&Kestrel{}), burn(&Auroch{})
+	if light == 0 || heavy == 0 || light == heavy {
+		t.Errorf("got Δv %v (Kestrel) and %v (Auroch)", light, heavy)
+	}
+}
+
+func TestEngineZeroElapsed(t *testing.T) {
+	e := devOrbitingShip()
+	m := S.Engine[e].(*MainEngine)
+	if err := m.SetThrust(maxThrustBaseKestrel); err != nil {
+		t.Fatal(err)
+	}
+	m.Update(spoolTimeBaseKestrel)
+
+	// at target thrust: no force rather than 0/0
+	if f, torque := m.Update(0); f != nil || torque != nil || m.Thrust() != maxThrustBaseKestrel {
+		t.Errorf("got force %v torque %v thrust %v", f, torque, m.Thrust())
+	}
+
+	S.AddForceGen(e, &EngineForceGen{timeLeft: 10})
+	updateClassicalMechanics(0, 0, S.EntFrames[e], e)
+	pos, vel := S.EntityState(e, 0)
+	if math.IsNaN(pos.Magnitude()) || math.IsNaN(vel.Magnitude()) {
+		t.Errorf("got position %v velocity %v", pos, vel)
+	}
+}
+
+// Force generators return the average force over the elapsed time, like
+// the engine: a second of thrust in a two second frame is half the thrust.
+func TestForceGenUnits(t *testing.T) {
+	e := devOrbitingShip()
+	S.Ori[e] = &Q{1, 0, 0, 0}
+	g := &ThrustForceGen{thrust: 1000, timeLeft: 1}
+	if f, _ := g.UpdateForce(e, 2); math.Abs(f.Magnitude()-500) > 1e-9 || !g.IsExpired() {
+		t.Errorf("got force %v", f.Magnitude())
+	}
+
+	turn := &TurnForceGen{torque: &V3{1000, 0, 0}, timeLeft: 1}
+	if _, torque := turn.UpdateForce(e, 2); math.Abs(torque.Magnitude()-500) > 1e-9 || !turn.IsExpired() {
+		t.Errorf("got torque %v", torque.Magnitude())
+	}
+
+	// Δv of thrust for seconds, off orbit
+	delete(S.Orb, e)
+	S.Pos[e], S.Vel[e] = new(V3), new(V3)
+	S.AddForceGen(e, &ThrustForceGen{thrust: 1000, timeLeft: 3})
+	for i := 0; i < 3; i++ {
+		updateClassicalMechanics(float64(i), 1.0, S.EntFrames[e], e)
+	}
+	if ex := 3000 / *S.Mass[e]; math.Abs(S.Vel[e].Magnitude()-ex) > 1e-9 {
+		t.Errorf("got Δv %v want %v", S.Vel[e].Magnitude(), ex)
+	}
+}
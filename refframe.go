@@ -68,3 +68,77 @@ type RefFrame struct {
 func (rf *RefFrame) IsRoot() bool {
 	return rf.Parent == nil
 }
+
+// LocalState returns the position and velocity of the frame's origin relative
+// its parent's origin, dt seconds from now.
+//
+// Frames directly under the root frame are positioned in galactic grid units
+// (see galaxy.go) while all other frames are positioned in meters.  The
+// returned vectors are always in meters and meters per second.
+func (rf *RefFrame) LocalState(dt float64) (*V3, *V3) {
+	switch {
+	case rf.Orbit != nil:
+		return rf.Orbit.AtTime(dt).OrbitalToStateVector()
+	case rf.Pos != nil:
+		pos := new(V3).Set(rf.Pos)
+		if rf.Parent != nil && rf.Parent.IsRoot() {
+			pos.MulScalar(pos, gridUnit*aum)
+		}
+		return pos, new(V3)
+	default:
+		return new(V3), new(V3)
+	}
+}
+
+// StateIn returns the position and velocity of the frame's origin relative
+// the origin of frame to, dt seconds from now.
+//
+// The frames are walked up to their closest common ancestor, which keeps
+// the precision of the result bound by the distance between the two frames
+// rather than their distance to the galactic origin.
+func (rf *RefFrame) StateIn(to *RefFrame, dt float64) (*V3, *V3) {
+	pos, vel := new(V3), new(V3)
+	a, b := rf, to
+	da, db := a.depth(), b.depth()
+
+	up := func(f *RefFrame, sign float64) *RefFrame {
+		p, v := f.LocalState(dt)
+		pos.AddScaledVector(p, sign)
+		vel.AddScaledVector(v, sign)
+		return f.Parent
+	}
+
+	for ; da > db; da-- {
+		a = up(a, 1)
+	}
+	for ; db > da; db-- {
+		b = up(b, -1)
+	}
+	for a != b {
+		a = up(a, 1)
+		b = up(b, -1)
+	}
+	return pos, vel
+}
+
+// depth returns the number of ancestors of the frame.
+func (rf *RefFrame) depth() int {
+	d := 0
+	for f := rf.Parent; f != nil; f = f.Parent {
+		d++
+	}
+	return d
+}
+
+// system returns the 2nd level frame (generally a star system) containing
+// the frame, or nil for the root frame.
+func (rf *RefFrame) system() *RefFrame {
+	if rf.IsRoot() {
+		return nil
+	}
+	f := rf
+	for !f.Parent.IsRoot() {
+		f = f.Parent
+	}
+	return f
+}
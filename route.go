@@ -0,0 +1,327 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Stellar route planning: A* search for a route of hyperspace jumps from a
+// ship to a target star, through the star systems of the star map.
+//
+// Only discovered stars are considered; stars are procedurally generated,
+// and added to S.StarsById, as players explore the sectors of the galaxy.
+// Jumps cannot cross the star systems of other stars (see interdiction.go),
+// and unexplored sectors may hide undiscovered star systems.
+
+// RouteOptions configures the route planner.  The cost of a jump is the
+// weighted sum of its distance, time and fuel, plus AvoidCost for each
+// avoided sector it passes through.  If all weights are zero, routes of
+// the shortest distance are planned.
+type RouteOptions struct {
+	// Max distance of a single jump in galactic grid units
+	MaxJump float64
+
+	// Cost per galactic grid unit, second and kg of fuel
+	DistanceCost float64
+	TimeCost     float64
+	FuelCost     float64
+
+	// Sectors to avoid and the cost of passing through one of them;
+	// +Inf forbids it
+	Avoid     []*Sector
+	AvoidCost float64
+
+	// Unexplored sectors are assumed clear, unless Pessimistic: then jumps
+	// cannot pass through them, other than out of the sector the ship is in
+	Pessimistic bool
+}
+
+// Route is a planned route of hyperspace jumps, arriving at the stars in
+// order.  Time includes the drive charge for each jump and the cooldown
+// between jumps.  Fuel is for the ship's current mass, and within the fuel
+// left.
+type Route struct {
+	Stars    []*Star
+	Distance float64 // galactic grid units
+	Time     float64 // s
+	Fuel     float64 // kg
+	Cost     float64
+}
+
+// routeNode is a position on the star map; the ship's position or a star.
+type routeNode struct {
+	star *Star
+	pos  *V3
+
+	cost  float64 // of the best route to the node found so far
+	fuel  float64 // burnt on that route
+	score float64 // cost plus the estimated cost to the target
+	prev  *routeNode
+	index int // in routeQueue, -1 if not queued
+	done  bool
+}
+
+// routeQueue is a priority queue of route nodes by score (container/heap)
+type routeQueue []*routeNode
+
+func (q routeQueue) Len() int           { return len(q) }
+func (q routeQueue) Less(i, j int) bool { return q[i].score < q[j].score }
+func (q routeQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index, q[j].index = i, j
+}
+
+func (q *routeQueue) Push(x interface{}) {
+	n := x.(*routeNode)
+	n.index = len(*q)
+	*q = append(*q, n)
+}
+
+func (q *routeQueue) Pop() interface{} {
+	old := *q
+	n := old[len(old)-1]
+	n.index = -1
+	*q = old[:len(old)-1]
+	return n
+}
+
+// PlanRoute returns the route of least cost for the ship to the target
+// star, with the ship's jump drive and the fuel left.  If the least cost
+// routes need more fuel than left, the shortest route that does not is
+// returned.
+func PlanRoute(e Id, target *Star, opts *RouteOptions) (*Route, error) {
+	drive := S.JumpDrive[e]
+	switch {
+	case drive == nil:
+		return nil, fmt.Errorf("entity %v has no jump drive", e)
+	case S.Hyperspace[e] != nil:
+		return nil, fmt.Errorf("entity %v already in hyperdrive", e)
+	case target == nil || S.StarsById[target.Entity] == nil:
+		return nil, errors.New("unknown target star")
+	case opts == nil:
+		return nil, errors.New("missing route options")
+	case !(opts.MaxJump > 0):
+		return nil, fmt.Errorf("invalid max jump %v", opts.MaxJump)
+	case opts.DistanceCost < 0 || opts.TimeCost < 0 || opts.FuelCost < 0 || opts.AvoidCost < 0:
+		return nil, errors.New("negative route cost")
+	case !(drive.Range(*S.Mass[e]) > 0):
+		return nil, errors.New("no fuel left for jump")
+	}
+	origin := systemStar(S.EntFrames[e])
+	if origin == target {
+		return nil, errors.New("already in the target star system")
+	}
+
+	p := &routePlanner{
+		RouteOptions: *opts,
+		drive:        drive,
+		mass:         *S.Mass[e],
+		origin:       origin,
+		target:       target,
+		avoid:        make(map[string]bool, len(opts.Avoid)),
+	}
+	p.MaxJump = math.Min(p.MaxJump, drive.Range(p.mass))
+	if p.DistanceCost == 0 && p.TimeCost == 0 && p.FuelCost == 0 {
+		p.DistanceCost = 1
+	}
+	for _, s := range opts.Avoid {
+		p.avoid[s.Key()] = true
+	}
+
+	start := &routeNode{pos: galacticPos(e)}
+	p.startSector = sectorKey(start.pos, true)
+	if goal := p.search(start); goal != nil {
+		return p.route(start, goal), nil
+	}
+
+	// The search keeps only the least cost route to each star, which may
+	// burn more fuel than another route on to the target.  The shortest
+	// routes burn the least fuel: search them, still avoiding forbidden
+	// sectors, and cost the route found as planned.
+	shortest := *p
+	shortest.DistanceCost, shortest.TimeCost, shortest.FuelCost = 1, 0, 0
+	if !math.IsInf(shortest.AvoidCost, 1) {
+		shortest.AvoidCost = 0
+	}
+	start = &routeNode{pos: start.pos}
+	if goal := shortest.search(start); goal != nil {
+		return p.route(start, goal), nil
+	}
+	return nil, fmt.Errorf("no route to star %v within jumps of %v and %.1f kg fuel", target.Entity, p.MaxJump, drive.Fuel)
+}
+
+// search returns the goal node of the route of least cost from start to the
+// target star, within the fuel left, or nil if there is none.
+func (p *routePlanner) search(start *routeNode) *routeNode {
+	nodes := []*routeNode{start}
+	var goal *routeNode
+	for _, st := range S.StarsById {
+		if st == p.origin {
+			continue
+		}
+		n := &routeNode{star: st, pos: S.Pos[st.Entity], cost: math.Inf(1), index: -1}
+		nodes = append(nodes, n)
+		if st == p.target {
+			goal = n
+		}
+	}
+	// deterministic among routes of equal cost
+	sort.Slice(nodes[1:], func(i, j int) bool {
+		return nodes[1+i].star.Entity < nodes[1+j].star.Entity
+	})
+
+	start.score = p.estimate(start.pos, goal.pos)
+	queue := &routeQueue{start}
+	for queue.Len() > 0 {
+		n := heap.Pop(queue).(*routeNode)
+		if n == goal {
+			return goal
+		}
+		n.done = true
+
+		for _, next := range nodes {
+			if next.done || next == n {
+				continue
+			}
+			fuel := n.fuel + p.drive.FuelNeeded(p.mass, new(V3).Sub(next.pos, n.pos).Magnitude())
+			if fuel > p.drive.Fuel {
+				continue
+			}
+			c := p.jumpCost(n, next, n == start)
+			if math.IsInf(c, 1) || n.cost+c >= next.cost {
+				continue
+			}
+			next.cost = n.cost + c
+			next.score = next.cost + p.estimate(next.pos, goal.pos)
+			next.fuel = fuel
+			next.prev = n
+			if next.index < 0 {
+				heap.Push(queue, next)
+			} else {
+				heap.Fix(queue, next.index)
+			}
+		}
+	}
+	return nil
+}
+
+// routePlanner holds the state of a route search.
+type routePlanner struct {
+	RouteOptions
+	drive          *JumpDrive
+	mass           float64
+	origin, target *Star
+	avoid          map[string]bool
+	startSector    string
+}
+
+// jumpTime returns the time in seconds of a jump of dist galactic grid
+// units at the drive's max speed, including the drive charge and, unless
+// first, the cooldown after the previous jump.
+func (p *routePlanner) jumpTime(dist float64, first bool) float64 {
+	t := p.drive.ChargeTime(p.mass) + (dist*gridUnit*aum)/(p.drive.Class.MaxSpeedCap()*speedOfLight)
+	if !first {
+		t += p.drive.Class.CooldownBase()
+	}
+	return t
+}
+
+// jumpCost returns the cost of the jump from node a to b, or +Inf if the
+// jump is not possible.
+func (p *routePlanner) jumpCost(a, b *routeNode, first bool) float64 {
+	dist := new(V3).Sub(b.pos, a.pos).Magnitude()
+	if dist > p.MaxJump {
+		return math.Inf(1)
+	}
+
+	from := a.star
+	if first {
+		from = p.origin
+	}
+	for _, st := range S.StarsById {
+		if st == from || st == b.star {
+			continue
+		}
+		r := S.EntFrames[st.Entity].Radius / (gridUnit * aum)
+		if SegmentIntersectsSphere(a.pos, b.pos, S.Pos[st.Entity], r) {
+			return math.Inf(1)
+		}
+	}
+
+	cost := p.DistanceCost*dist +
+		p.TimeCost*p.jumpTime(dist, first) +
+		p.FuelCost*p.drive.FuelNeeded(p.mass, dist)
+
+	for key := range sectorsCrossed(a.pos, b.pos) {
+		if p.avoid[key] && p.AvoidCost > 0 {
+			cost += p.AvoidCost
+		}
+		if p.Pessimistic && key != p.startSector {
+			if s := S.Sectors[key]; s == nil || s.Mapped == 0 {
+				return math.Inf(1)
+			}
+		}
+	}
+	return cost
+}
+
+// estimate returns a lower bound of the cost of the route from a to b: a
+// straight line in as few jumps as possible.
+func (p *routePlanner) estimate(a, b *V3) float64 {
+	dist := new(V3).Sub(b, a).Magnitude()
+	jumps := math.Ceil(dist / p.MaxJump)
+	transit := (dist * gridUnit * aum) / (p.drive.Class.MaxSpeedCap() * speedOfLight)
+	return p.DistanceCost*dist +
+		p.TimeCost*(transit+jumps*p.drive.ChargeTime(p.mass)) +
+		p.FuelCost*p.drive.FuelNeeded(p.mass, dist)
+}
+
+// route returns the route found from start to goal, costed by the
+// planner's options.
+func (p *routePlanner) route(start, goal *routeNode) *Route {
+	r := &Route{}
+	for n := goal; n != start; n = n.prev {
+		dist := new(V3).Sub(n.pos, n.prev.pos).Magnitude()
+		r.Stars = append([]*Star{n.star}, r.Stars...)
+		r.Distance += dist
+		r.Time += p.jumpTime(dist, n.prev == start)
+		r.Fuel += p.drive.FuelNeeded(p.mass, dist)
+		r.Cost += p.jumpCost(n.prev, n, n.prev == start)
+	}
+	r.Time += math.Max(0, p.drive.ReadyAt-S.WorldTime)
+	return r
+}
+
+// sectorsCrossed returns the keys of the sectors the line segment from a to
+// b, in galactic grid units, passes through.  The segment is sampled every
+// routeSectorStep sector sizes, and may clip the corners of sectors
+// unnoticed.
+func sectorsCrossed(a, b *V3) map[string]bool {
+	ab := new(V3).Sub(b, a)
+	steps := math.Ceil(ab.Magnitude() / (routeSectorStep * sectorSize / gridUnit))
+	keys := map[string]bool{sectorKey(a, true): true}
+	for i := 1.0; i <= steps; i++ {
+		keys[sectorKey(new(V3).Set(a).AddScaledVector(ab, i/steps), true)] = true
+	}
+	return keys
+}
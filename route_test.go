@@ -0,0 +1,205 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"math"
+	"testing"
+)
+
+// addRouteStar adds a star at offset, in galactic grid units, from the
+// star of the ship's star system.
+func addRouteStar(e Id, offset *V3) *Star {
+	pos := new(V3).Add(S.Pos[systemStar(S.EntFrames[e]).Entity], offset)
+	star := NewStar(0.5)
+	star.Entity = S.NewEntity()
+	GetSector(pos).addStarFixed(star, pos)
+	return star
+}
+
+func routeStars(r *Route) []Id {
+	ids := []Id{}
+	for _, st := range r.Stars {
+		ids = append(ids, st.Entity)
+	}
+	return ids
+}
+
+func sameRoute(r *Route, stars ...*Star) bool {
+	if len(r.Stars) != len(stars) {
+		return false
+	}
+	for i, st := range stars {
+		if r.Stars[i] != st {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPlanRoute(t *testing.T) {
+	e, _ := devHyperdriveWorld()
+	// a chain of short jumps, or two long jumps around it
+	a := addRouteStar(e, &V3{4, 0, 0})
+	b := addRouteStar(e, &V3{8, 0, 0})
+	target := addRouteStar(e, &V3{12, 0, 0})
+	m := addRouteStar(e, &V3{6, 5, 0})
+
+	if _, err := PlanRoute(e, target, &RouteOptions{MaxJump: 3}); err == nil {
+		t.Errorf("expected no route")
+	}
+
+	r, err := PlanRoute(e, target, &RouteOptions{MaxJump: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameRoute(r, a, b, target) {
+		t.Errorf("shortest route through %v", routeStars(r))
+	}
+	if math.Abs(r.Distance-11.95) > 1e-6 || r.Cost != r.Distance {
+		t.Errorf("route distance %v cost %v", r.Distance, r.Cost)
+	}
+	d := S.JumpDrive[e]
+	mass := *S.Mass[e]
+	exTime := 3*d.ChargeTime(mass) + 2*cooldownBaseCourier +
+		r.Distance*gridUnit*aum/(maxSpeedCapCourier*speedOfLight)
+	if math.Abs(r.Time-exTime) > 1e-6 || math.Abs(r.Fuel-d.FuelNeeded(mass, r.Distance)) > 1e-9 {
+		t.Errorf("route time %v fuel %v, expected time %v", r.Time, r.Fuel, exTime)
+	}
+
+	// fewer jumps are faster, the drive charging and cooling down
+	r, err = PlanRoute(e, target, &RouteOptions{MaxJump: 8, TimeCost: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameRoute(r, m, target) || r.Cost != r.Time {
+		t.Errorf("fastest route through %v", routeStars(r))
+	}
+}
+
+func TestPlanRouteAvoid(t *testing.T) {
+	e, _ := devHyperdriveWorld()
+	// the dev star is just inside the sector corner at X, Y = 0, 0
+	a := addRouteStar(e, &V3{-5.5, 0.5, 0})
+	b := addRouteStar(e, &V3{1, -6, 0})
+	target := addRouteStar(e, &V3{-6, -6, 0})
+	opts := &RouteOptions{MaxJump: 7.5}
+
+	r, err := PlanRoute(e, target, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameRoute(r, a, target) {
+		t.Errorf("shortest route through %v", routeStars(r))
+	}
+
+	opts.Avoid = []*Sector{GetSector(S.Pos[a.Entity])}
+	// both jumps pass through the sector, about a grid unit shorter
+	opts.AvoidCost = 0.25
+	if r, _ := PlanRoute(e, target, opts); !sameRoute(r, a, target) {
+		t.Errorf("route through %v, avoided sector worth the detour", routeStars(r))
+	}
+	opts.AvoidCost = math.Inf(1)
+	if r, _ := PlanRoute(e, target, opts); !sameRoute(r, b, target) {
+		t.Errorf("route through %v, expected around the avoided sector", routeStars(r))
+	}
+}
+
+func TestPlanRouteUnexplored(t *testing.T) {
+	e, _ := devHyperdriveWorld()
+	// two sectors along X, past an unexplored sector
+	size := sectorSize / gridUnit
+	target := addRouteStar(e, &V3{2.5 * size, 0, 0})
+	GetSector(S.Pos[target.Entity]).Mapped = 0.5
+	opts := &RouteOptions{MaxJump: 3 * size}
+
+	if r, err := PlanRoute(e, target, opts); err != nil || !sameRoute(r, target) {
+		t.Errorf("optimistic route %v, %v", r, err)
+	}
+	opts.Pessimistic = true
+	if _, err := PlanRoute(e, target, opts); err == nil {
+		t.Errorf("expected no route through the unexplored sector")
+	}
+	GetSector(new(V3).Add(S.Pos[target.Entity], &V3{-size, 0, 0})).Mapped = 0.25
+	if r, err := PlanRoute(e, target, opts); err != nil || !sameRoute(r, target) {
+		t.Errorf("pessimistic route %v, %v", r, err)
+	}
+}
+
+func TestPlanRouteFuel(t *testing.T) {
+	e, _ := devHyperdriveWorld()
+	a := addRouteStar(e, &V3{4, 0, 0})
+	target := addRouteStar(e, &V3{8, 0, 0})
+	d := S.JumpDrive[e]
+	mass := *S.Mass[e]
+	opts := &RouteOptions{MaxJump: 10}
+
+	if _, err := PlanRoute(e, target, nil); err == nil {
+		t.Errorf("expected error for missing options")
+	}
+
+	// fuel for the route
+	d.Fuel = d.FuelNeeded(mass, 8.5)
+	r, err := PlanRoute(e, target, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Stars[len(r.Stars)-1] != target || r.Fuel > d.Fuel {
+		t.Errorf("route through %v needs %v kg fuel, %v left", routeStars(r), r.Fuel, d.Fuel)
+	}
+
+	// jumps are limited to the drive's range
+	d.Fuel = d.FuelNeeded(mass, 6)
+	if _, err := PlanRoute(e, target, opts); err == nil {
+		t.Errorf("expected no route beyond the drive's range")
+	}
+	if r, err := PlanRoute(e, a, opts); err != nil || !sameRoute(r, a) {
+		t.Errorf("route within range %v, %v", r, err)
+	}
+
+	d.Fuel = 0
+	if _, err := PlanRoute(e, a, opts); err == nil {
+		t.Errorf("expected error with an empty tank")
+	}
+}
+
+func TestPlanRouteFuelFallback(t *testing.T) {
+	e, _ := devHyperdriveWorld()
+	// a chain of short jumps to x, or two long jumps around it, and on to
+	// the target
+	a := addRouteStar(e, &V3{4, 0, 0})
+	b := addRouteStar(e, &V3{8, 0, 0})
+	x := addRouteStar(e, &V3{12, 0, 0})
+	target := addRouteStar(e, &V3{16.5, 0, 0})
+	addRouteStar(e, &V3{6, 5, 0})
+	d := S.JumpDrive[e]
+	opts := &RouteOptions{MaxJump: 8, TimeCost: 1}
+
+	// the fast route to x leaves too little fuel for the target
+	d.Fuel = d.FuelNeeded(*S.Mass[e], 17)
+	r, err := PlanRoute(e, target, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameRoute(r, a, b, x, target) || r.Fuel > d.Fuel {
+		t.Errorf("route through %v needs %v kg fuel, %v left", routeStars(r), r.Fuel, d.Fuel)
+	}
+	if math.Abs(r.Cost-r.Time) > 1e-9 {
+		t.Errorf("route cost %v, expected its time %v", r.Cost, r.Time)
+	}
+}
@@ -52,7 +52,7 @@ type ShipClass interface {
 	// The ship volume when packed inside a cargo bay or other storage.
 	PackedVolumeBase() float64
 
-	// Control Moment Gyroscope (CMG) Max Torque in newtons (N).
+	// Control Moment Gyroscope (CMG) Max Torque in newton-meters (N·m).
 	//
 	// This is a built-in, non-modular engine situated at the ship's
 	// center of mass.  The CMG has a single function: generate torque around
@@ -69,6 +69,21 @@ type ShipClass interface {
 	// even in zero-g/vacuum (https://en.wikipedia.org/wiki/Banked_turn).
 	CMGTorqueCap() V3
 
+	// CMG Max Angular Momentum in newton-meter-seconds (N·m·s).
+	//
+	// The momentum the CMG can store per axis before saturating.
+	// A saturated CMG cannot deliver more torque in the same direction
+	// until desaturated, see cmg.go.
+	CMGMomentumCap() V3
+
+	// Reaction Control System (RCS) Max Torque in newton-meters (N·m).
+	// Used to desaturate the CMG.
+	RCSTorqueCap() V3
+
+	// Magnetorquer Max Magnetic Dipole Moment in ampere square meters
+	// (A·m^2).  Used to desaturate the CMG in a planetary magnetic field.
+	MagnetorquerDipoleCap() float64
+
 	// Hull/Armor/Shield Capacity in hit points.
 	HullHPCap() float64
 	//ArmorHPCap float64
@@ -108,9 +123,29 @@ type ShipClass interface {
 	// TODO: ship bonuses
 }
 
+// Engine is implemented by engine modules fitted to ships.
+// See propulsion.go.
 type Engine interface {
+	// Max and min non-zero thrust in newtons (N).
 	MaxThrust() float64
+	MinThrust() float64
+
+	// Thrust returns the current thrust in newtons (N).
+	Thrust() float64
+
+	// SetThrust sets the thrust the engine spools towards.
+	// An error is returned if the thrust is outside the throttle range.
 	SetThrust(float64) error
+
+	// SetGimbal sets the gimbal angles in radians around the ship's
+	// X (pitch) and Y (yaw) axes.
+	// An error is returned if an angle is outside the gimbal range.
+	SetGimbal(pitch, yaw float64) error
+
+	// Update advances the engine elapsed seconds and returns the average
+	// force and torque in body space over that time.
+	// Zero force/torque is returned as nil.
+	Update(elapsed float64) (*V3, *V3)
 }
 
 type WarmJet struct{}
@@ -123,10 +158,20 @@ const (
 	volumeBaseWarmjet       = 60 // m3
 	packedVolumeBaseWarmjet = 60 // m3
 
-	cmgTorqueCapXWarmjet = 1000000.0 // Newton (N)
+	cmgTorqueCapXWarmjet = 1000000.0 // Newton meter (N·m)
 	cmgTorqueCapYWarmjet = 1000000.0
 	cmgTorqueCapZWarmjet = 1000000.0
 
+	cmgMomentumCapXWarmjet = 20000000.0 // Newton meter second (N·m·s)
+	cmgMomentumCapYWarmjet = 20000000.0
+	cmgMomentumCapZWarmjet = 20000000.0
+
+	rcsTorqueCapXWarmjet = 200000.0 // Newton meter (N·m)
+	rcsTorqueCapYWarmjet = 200000.0
+	rcsTorqueCapZWarmjet = 200000.0
+
+	magnetorquerDipoleCapWarmjet = 50000.0 // ampere square meter (A·m^2)
+
 	hullHPCapWarmjet = 100 // hit points
 
 	cargoBayCapWarmjet = 10 // m3
@@ -148,10 +193,17 @@ func (s *WarmJet) PackedVolumeBase() float64     { return packedVolumeBaseWarmje
 func (s *WarmJet) CMGTorqueCap() V3 {
 	return V3{cmgTorqueCapXWarmjet, cmgTorqueCapYWarmjet, cmgTorqueCapZWarmjet}
 }
-func (s *WarmJet) HullHPCap() float64    { return hullHPCapWarmjet }
-func (s *WarmJet) CargoBayCap() float64  { return cargoBayCapWarmjet }
-func (s *WarmJet) AeroLiftBase() float64 { return aeroLiftBaseWarmjet }
-func (s *WarmJet) AeroDragBase() float64 { return aeroDragBaseWarmjet }
-func (s *WarmJet) HardPoints() uint8     { return hardPointsWarmjet }
-func (s *WarmJet) HighPowerSlots() uint8 { return highPowerSlotsWarmjet }
-func (s *WarmJet) LowPowerSlots() uint8  { return lowPowerSlotsWarmjet }
+func (s *WarmJet) CMGMomentumCap() V3 {
+	return V3{cmgMomentumCapXWarmjet, cmgMomentumCapYWarmjet, cmgMomentumCapZWarmjet}
+}
+func (s *WarmJet) RCSTorqueCap() V3 {
+	return V3{rcsTorqueCapXWarmjet, rcsTorqueCapYWarmjet, rcsTorqueCapZWarmjet}
+}
+func (s *WarmJet) MagnetorquerDipoleCap() float64 { return magnetorquerDipoleCapWarmjet }
+func (s *WarmJet) HullHPCap() float64             { return hullHPCapWarmjet }
+func (s *WarmJet) CargoBayCap() float64           { return cargoBayCapWarmjet }
+func (s *WarmJet) AeroLiftBase() float64          { return aeroLiftBaseWarmjet }
+func (s *WarmJet) AeroDragBase() float64          { return aeroDragBaseWarmjet }
+func (s *WarmJet) HardPoints() uint8              { return hardPointsWarmjet }
+func (s *WarmJet) HighPowerSlots() uint8          { return highPowerSlotsWarmjet }
+func (s *WarmJet) LowPowerSlots() uint8           { return lowPowerSlotsWarmjet }
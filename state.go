@@ -20,6 +20,7 @@ package tesseract
 import (
 	"encoding/json"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 )
 
@@ -47,6 +48,12 @@ type State struct {
 	EntCount  uint64
 	EntFrames map[Id]*RefFrame
 
+	// WorldTime is the game world time in seconds, advanced by the engine
+	WorldTime float64
+
+	// Timers holds actions scheduled for a future world time
+	Timers *TimerComponent
+
 	HotEnts  map[*RefFrame]map[Id]bool
 	IdleEnts map[*RefFrame]map[Id]bool
 
@@ -58,11 +65,29 @@ type State struct {
 	// Hyperspace component holds data used by the Hyperdrive System
 	Hyperspace map[Id]*Hyperspace
 
+	// Interstellar holds the ref frames created for entities outside star
+	// systems (see interstellar.go)
+	Interstellar map[*RefFrame]bool
+
+	// Interdictors holds the entities with an active interdiction field
+	Interdictors map[Id]bool
+
 	StarsById   map[Id]*Star
 	StarsByName map[string]*Star
 
 	Sectors map[string]*Sector
 
+	// Nonces holds the last action nonce of each user account
+	Nonces *NonceTracker
+
+	// Owner component holds the owning user account of entities and
+	// Delegates the accounts the owner delegated control to
+	Owner     map[Id]common.Address
+	Delegates map[Id]map[common.Address]bool
+
+	// Planets by entity id.  The planet is at the origin of its ref frame.
+	Planets map[Id]*Planet
+
 	//
 	// Physics Components
 	//
@@ -87,29 +112,45 @@ type State struct {
 
 	// Holds ship class data
 	ShipClass map[Id]ShipClass
+
+	// Holds angular momentum stored in ship control moment gyroscopes
+	CMG map[Id]*CMG
+
+	// Holds engines fitted to ships
+	Engine map[Id]Engine
+
+	// Holds jump drives fitted to ships
+	JumpDrive map[Id]*JumpDrive
 }
 
 func ResetState() {
 	s := new(State)
 
-	channels := make([]chan<- []byte, 0)
-	s.MsgBus = &MessageBus{channels}
-	channels2 := make([]chan<- []byte, 0)
-	s.ActionBus = &MessageBus{channels2}
+	channels := make([]chan []byte, 0)
+	s.MsgBus = &MessageBus{channels: channels}
+	channels2 := make([]chan []byte, 0)
+	s.ActionBus = &MessageBus{channels: channels2}
 
 	s.EntitySubs = make(map[*EntitySub]bool, 0)
 	s.EntitySubsCloseChan = make(chan *EntitySub, 100)
 
 	s.EntFrames = make(map[Id]*RefFrame, 0)
+	s.Timers = NewTimerComponent()
 
 	s.HotEnts = make(map[*RefFrame]map[Id]bool, 0)
 	s.IdleEnts = make(map[*RefFrame]map[Id]bool, 0)
 	s.IdleSince = make(map[Id]float64, 0)
 
 	s.Hyperspace = make(map[Id]*Hyperspace, 0)
+	s.Interstellar = make(map[*RefFrame]bool, 0)
+	s.Interdictors = make(map[Id]bool, 0)
 	s.StarsById = make(map[Id]*Star, 0)
 	s.StarsByName = make(map[string]*Star, 0)
 	s.Sectors = make(map[string]*Sector, 0)
+	s.Nonces = NewNonceTracker()
+	s.Owner = make(map[Id]common.Address, 0)
+	s.Delegates = make(map[Id]map[common.Address]bool, 0)
+	s.Planets = make(map[Id]*Planet, 0)
 	s.Mass = make(map[Id]*float64, 0)
 	s.Pos = make(map[Id]*V3, 0)
 	s.Vel = make(map[Id]*V3, 0)
@@ -118,6 +159,9 @@ func ResetState() {
 	s.ForceGens = make(map[Id][]ForceGen, 0)
 	s.Rot = make(map[Id]*Rotational, 0)
 	s.ShipClass = make(map[Id]ShipClass, 0)
+	s.CMG = make(map[Id]*CMG, 0)
+	s.Engine = make(map[Id]Engine, 0)
+	s.JumpDrive = make(map[Id]*JumpDrive, 0)
 	S = s
 }
 
@@ -159,6 +203,49 @@ func (s *State) AddStar(star *Star, pos *V3) {
 	s.SetIdle(star.Entity, S.EntFrames[star.Entity], 0)
 }
 
+func (s *State) AddPlanet(p *Planet, rf *RefFrame) {
+	s.Planets[p.Entity] = p
+	s.EntFrames[p.Entity] = rf
+}
+
+// PlanetInFrame returns the planet at the origin of the ref frame, if any.
+func (s *State) PlanetInFrame(rf *RefFrame) *Planet {
+	for e, p := range s.Planets {
+		if s.EntFrames[e] == rf {
+			return p
+		}
+	}
+	return nil
+}
+
+// StarInFrame returns the star at the origin of the ref frame, if any.
+func (s *State) StarInFrame(rf *RefFrame) *Star {
+	for e, st := range s.StarsById {
+		if s.EntFrames[e] == rf {
+			return st
+		}
+	}
+	return nil
+}
+
+// EntityState returns the position and velocity of the entity in its
+// ref frame, dt seconds from now.
+func (s *State) EntityState(e Id, dt float64) (*V3, *V3) {
+	if s.Orb[e] != nil {
+		return s.Orb[e].AtTime(dt).OrbitalToStateVector()
+	}
+
+	pos, vel := new(V3), new(V3)
+	if s.Pos[e] != nil {
+		pos.Set(s.Pos[e])
+	}
+	if s.Vel[e] != nil {
+		vel.Set(s.Vel[e])
+	}
+	pos.AddScaledVector(vel, dt)
+	return pos, vel
+}
+
 func (s *State) AddForceGen(e Id, fg ForceGen) {
 	s.ForceGens[e] = append(s.ForceGens[e], fg)
 	rf := s.EntFrames[e]
@@ -173,21 +260,30 @@ func (s *State) AddEntitySub(e Id) {
 //
 // JSON Encoding
 //
+// The field names of EntJSON and RefFrameJSON are the names clients read
+// (Id, Mas, Pos, ...); they are not tagged.
 type EntJSON struct {
-	Id  Id       `json: "id"`
-	Mas *float64 `json: "mass"`
-	Pos *V3      `json: "pos"`
-	Vel *V3      `json: "vel"`
-	Ori *Q       `json: "ori"`
-	Rot *V3      `json: "rot"`
+	Id  Id
+	Mas *float64
+	Pos *V3
+	Vel *V3
+	Ori *Q
+	Rot *V3
 }
 
 type RefFrameJSON struct {
-	Ents []EntJSON `json: "ents"`
+	Ents []EntJSON
+}
+
+type StateJSON struct {
+	WorldTime float64         `json:"worldTime"`
+	Frames    []RefFrameJSON  `json:"frames"`
+	Timers    *TimerComponent `json:"timers"`
 }
 
-// Encode state as an array of reference frames, each having
-// an array of entities where each entity has mass, position, etc.
+// Encode state as the world time, pending timers and an array of
+// reference frames, each having an array of entities where each entity
+// has mass, position, etc.
 // TODO: for now, we assume all entities have all components
 func (s *State) MarshalJSON() ([]byte, error) {
 	rfJSONs := make([]RefFrameJSON, 0)
@@ -204,5 +300,5 @@ func (s *State) MarshalJSON() ([]byte, error) {
 		rfJSON.Ents = append(rfJSON.Ents, entJSON)
 	}
 	rfJSONs = append(rfJSONs, rfJSON)
-	return json.Marshal(rfJSONs)
+	return json.Marshal(StateJSON{s.WorldTime, rfJSONs, s.Timers})
 }
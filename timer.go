@@ -1,69 +1,183 @@
 /*  Copyright 2019 The tesseract Authors
-	
+
     This file is part of tesseract.
-    
+
     tesseract is free software: you can redistribute it and/or modify
     it under the terms of the GNU Affero General Public License as
     published by the Free Software Foundation, either version 3 of the
     License, or (at your option) any later version.
-	
+
     tesseract is distributed in the hope that it will be useful,
     but WITHOUT ANY WARRANTY; without even the implied warranty of
     MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
     GNU Affero General Public License for more details.
-	
+
     You should have received a copy of the GNU Affero General Public License
     along with this program.  If not, see <https://www.gnu.org/licenses/>.
 */
 package tesseract
 
 import (
-    "sort"
-    "time"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
 // Timers are actions scheduled for future execution.
 
 // The timer component is attachable to any entity and used for things like
-// delayed-effect weapons, manufacturing processes and skill training.
+// delayed-effect weapons, manufacturing processes, skill training and
+// maneuvers scheduled for an orbital event.
+//
+// A timer fires in the first engine frame at or after its world time.
+// Timers due in the same frame fire in order of world time, then in the
+// order they were scheduled, so the outcome is deterministic.
+//
+// Timers hold the envelope of their action rather than only the decoded
+// action, so they can be encoded with the rest of the state and decoded
+// again when it is restored.
 type Timer struct {
-    Id Id
-    MS time.Time
-    Action *Action
+	Id     Id      `json:"id"`
+	Entity Id      `json:"entity"`
+	At     float64 `json:"at"` // world time (s)
+
+	// Event is the orbital event At was derived from, if any
+	Event string `json:"event,omitempty"`
+
+	// Signer is the account that scheduled the timer.  It must still be
+	// authorized for the entity when the timer fires.
+	Signer   common.Address  `json:"signer"`
+	Envelope *ActionEnvelope `json:"action"`
+
+	action  Action
+	results chan<- *ActionResult
 }
 
-// Alongside the map of entity ids to Timers, we also maintain
-// this list (slice) of Timers Sorted in chronological order.
+// Alongside the map of timer ids to Timers, we also maintain
+// this list (slice) of Timers Sorted in firing order.
 // The engine loop uses this to only traverse expired Timers.
 type TimerList []*Timer
+
 // sort.Interface
-func (tl TimerList) Len() int           { return len(tl) }
-func (tl TimerList) Swap(i, j int)      { tl[i], tl[j] = tl[j], tl[i] }
-func (tl TimerList) Less(i, j int) bool { return tl[i].MS.Before(tl[j].MS) }
+func (tl TimerList) Len() int      { return len(tl) }
+func (tl TimerList) Swap(i, j int) { tl[i], tl[j] = tl[j], tl[i] }
+func (tl TimerList) Less(i, j int) bool {
+	if tl[i].At != tl[j].At {
+		return tl[i].At < tl[j].At
+	}
+	return tl[i].Id < tl[j].Id
+}
 
 type TimerComponent struct {
-    Timers map[Id]*Timer
-    Sorted TimerList
+	Timers map[Id]*Timer
+	Sorted TimerList
+
+	lastId Id
+}
+
+func NewTimerComponent() *TimerComponent {
+	return &TimerComponent{Timers: make(map[Id]*Timer)}
 }
 
 func (tc *TimerComponent) Init() error {
-    return nil
+	return nil
+}
+
+// Add assigns the timer an id and schedules it.
+func (tc *TimerComponent) Add(t *Timer) Id {
+	tc.lastId++
+	t.Id = tc.lastId
+	tc.insert(t)
+	return t.Id
+}
+
+func (tc *TimerComponent) insert(t *Timer) {
+	tc.Timers[t.Id] = t
+	i := sort.Search(len(tc.Sorted), func(i int) bool {
+		return TimerList{t, tc.Sorted[i]}.Less(0, 1)
+	})
+	tc.Sorted = append(tc.Sorted, nil)
+	copy(tc.Sorted[i+1:], tc.Sorted[i:])
+	tc.Sorted[i] = t
+}
+
+func (tc *TimerComponent) Get(id Id) *Timer {
+	return tc.Timers[id]
+}
+
+// Cancel removes the timer.  It returns false if there is no such timer.
+func (tc *TimerComponent) Cancel(id Id) bool {
+	t := tc.Timers[id]
+	if t == nil {
+		return false
+	}
+	delete(tc.Timers, id)
+	for i, t2 := range tc.Sorted {
+		if t2 == t {
+			tc.Sorted = append(tc.Sorted[:i], tc.Sorted[i+1:]...)
+			break
+		}
+	}
+	return true
 }
 
-func (tc *TimerComponent) AddEntityData(id Id, t *Timer) {
-    tc.Timers[id] = t
-    tc.Sorted = append(tc.Sorted, t)
-    sort.Stable(tc.Sorted)
+// ForEntity returns the timers of entity e in firing order.
+func (tc *TimerComponent) ForEntity(e Id) []*Timer {
+	timers := []*Timer{}
+	for _, t := range tc.Sorted {
+		if t.Entity == e {
+			timers = append(timers, t)
+		}
+	}
+	return timers
 }
 
-func (tc *TimerComponent) GetEntityData(id Id) (*Timer) {
-    return tc.Timers[id]
+// Expire removes and returns, in firing order, all timers due at or
+// before worldTime.
+func (tc *TimerComponent) Expire(worldTime float64) []*Timer {
+	n := sort.Search(len(tc.Sorted), func(i int) bool {
+		return tc.Sorted[i].At > worldTime
+	})
+	expired := make([]*Timer, n)
+	copy(expired, tc.Sorted[:n])
+	tc.Sorted = tc.Sorted[n:]
+	for _, t := range expired {
+		delete(tc.Timers, t.Id)
+	}
+	return expired
 }
 
-func (tc *TimerComponent) RemoveEntity(id Id) {
-    delete(tc.Timers, id)
+type timerComponentJSON struct {
+	LastId Id       `json:"lastId"`
+	Timers []*Timer `json:"timers"`
+}
+
+func (tc *TimerComponent) MarshalJSON() ([]byte, error) {
+	return json.Marshal(timerComponentJSON{tc.lastId, tc.Sorted})
+}
 
-    f := func(i int) bool {return tc.Sorted[i].Id == id}
-    i := sort.Search(len(tc.Sorted), f)
-    tc.Sorted = append(tc.Sorted[:i], tc.Sorted[i+1:]...)
+// UnmarshalJSON restores timers, decoding their actions again from the
+// action envelopes.
+func (tc *TimerComponent) UnmarshalJSON(b []byte) error {
+	var j timerComponentJSON
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	tc.Timers = make(map[Id]*Timer, len(j.Timers))
+	tc.Sorted = nil
+	tc.lastId = j.LastId
+	for _, t := range j.Timers {
+		if t.Envelope == nil {
+			return fmt.Errorf("timer %v has no action", t.Id)
+		}
+		a, err := t.Envelope.decode()
+		if err != nil {
+			return fmt.Errorf("timer %v: %v", t.Id, err)
+		}
+		t.action = a
+		tc.insert(t)
+	}
+	return nil
 }
@@ -0,0 +1,143 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestTimerOrder(t *testing.T) {
+	tc := NewTimerComponent()
+	ats := []float64{5, 1, 5, 3, 1}
+	for i, at := range ats {
+		tc.Add(&Timer{Entity: Id(i % 2), At: at})
+	}
+
+	if n := len(tc.ForEntity(1)); n != 2 {
+		t.Errorf("%d timers for entity 1, want 2", n)
+	}
+	if !tc.Cancel(4) || tc.Cancel(4) {
+		t.Errorf("cancel of timer 4 should succeed exactly once")
+	}
+
+	// same world time fires in scheduling order
+	want := []Id{2, 5, 1, 3}
+	expired := tc.Expire(5)
+	if len(expired) != len(want) {
+		t.Fatalf("%d timers expired, want %d", len(expired), len(want))
+	}
+	for i, timer := range expired {
+		if timer.Id != want[i] {
+			t.Errorf("timer %d fired at position %d, want %d", timer.Id, i, want[i])
+		}
+	}
+	if len(tc.Timers) != 0 || len(tc.Sorted) != 0 {
+		t.Errorf("expired timers not removed")
+	}
+}
+
+func TestScheduleAtPeriapsis(t *testing.T) {
+	e := devOrbitingShip()
+	owner := common.HexToAddress("0x01")
+	S.SetOwner(e, owner)
+	S.Orb[e].e = 0.1
+	S.Orb[e].θ = 1.0
+	S.WorldTime = 100
+
+	msg := `{"version":1,"id":7,"action":"schedule","entity":"` + strconv.FormatUint(uint64(e), 10) + `","params":
+		{"event":"periapsis","action":{"action":"thrust","params":{"force":100000,"duration":10}}}}`
+	a, err := DecodeAction([]byte(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ge := &GameEngine{actionChan: make(chan *ActionRequest, 1)}
+	results := make(chan *ActionResult, 2)
+	ge.executeAction(&ActionRequest{Id: 7, Action: a, Results: results, Signer: owner})
+	r := <-results
+	if r.Status != ActionApplied {
+		t.Fatalf("unexpected result %+v", r)
+	}
+	timer := S.Timers.Get(r.Data.(Id))
+	dt, _ := S.Orb[e].TimeToTrueAnomaly(0)
+	if timer == nil || math.Abs(timer.At-(100+dt)) > 1e-6 || timer.Event != EventPeriapsis {
+		t.Fatalf("unexpected timer %+v", timer)
+	}
+
+	// timers survive encoding with the state
+	b, err := json.Marshal(S)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot struct {
+		Timers *TimerComponent `json:"timers"`
+	}
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	restored := snapshot.Timers.Get(timer.Id)
+	if restored == nil || restored.At != timer.At || restored.Signer != owner {
+		t.Fatalf("timer not restored: %+v", restored)
+	}
+	if _, ok := restored.action.(*ActionEngineThrust); !ok {
+		t.Fatalf("restored timer action %T", restored.action)
+	}
+	S.Timers = snapshot.Timers
+
+	S.WorldTime = timer.At - 1
+	ge.handleTimerActions()
+	if len(S.Timers.Timers) != 1 {
+		t.Fatalf("timer fired early")
+	}
+	S.WorldTime = timer.At
+	ge.handleTimerActions()
+	if len(S.Timers.Timers) != 0 || ge.Metrics().ActionsApplied != 2 {
+		t.Fatalf("timer did not fire: %+v", ge.Metrics())
+	}
+	burning := false
+	for _, fg := range S.ForceGens[e] {
+		_, burning = fg.(*EngineForceGen)
+	}
+	if !burning {
+		t.Errorf("scheduled thrust not applied")
+	}
+}
+
+func TestTimerAuthorizedAtFiring(t *testing.T) {
+	e := devOrbitingShip()
+	owner := common.HexToAddress("0x01")
+	S.SetOwner(e, owner)
+
+	ge := &GameEngine{}
+	results := make(chan *ActionResult, 2)
+	a := &ActionSchedule{entity: e, at: 10, env: &ActionEnvelope{}, action: &ActionEngineGimbal{e, 0, 0}}
+	ge.executeAction(&ActionRequest{Id: 1, Action: a, Results: results, Signer: owner})
+	<-results
+
+	S.SetOwner(e, common.HexToAddress("0x02"))
+	S.WorldTime = 10
+	ge.handleTimerActions()
+	if r := <-results; r.Status != ActionRejected {
+		t.Errorf("timer of previous owner not rejected: %+v", r)
+	}
+}
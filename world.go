@@ -20,13 +20,33 @@ package tesseract
 import (
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 )
 
 func InitWorld() {
 }
 
-func DevWorld(testSeed uint64) {
+func DevWorld(testSeed uint64, owner common.Address) {
+	NewDevWorld(testSeed, owner)
+	StartEngine()
+
+	// TODO: begin stationary relative top-level galactic grid
+	// TODO: implement hyperdrive in any direction; sector traversal triggering
+	//       star procgen
+	//
+	// TODO: and then - hyperdrive to a new star; triggering system procgen! :D  for E
+	//
+	//DevHyperdrive()
+
+	// TODO: this comes after system procgen
+	//DevShipOrbit()
+}
+
+// NewDevWorld resets the state to the dev world, without starting the
+// engine, and returns the dev ship, owned by account owner.  The zero
+// address leaves the ship without owner; only the engine controls it.
+func NewDevWorld(testSeed uint64, owner common.Address) Id {
 	seed := testSeed
 	if testSeed == 0 {
 		seed = uint64(time.Now().Nanosecond())
@@ -66,37 +86,27 @@ func DevWorld(testSeed uint64) {
 		Orbit:       star.DefaultOrbit(),
 		Orientation: nil, // TODO
 	}
-	S.EntFrames[devMars.Entity] = devMarsRF
+	S.AddPlanet(devMars, devMarsRF)
 	
 
 	e := DevNewShip()
 	S.EntFrames[e] = devMarsRF
 	S.Orb[e] = devMars.DefaultOrbit()
+	if owner != (common.Address{}) {
+		S.SetOwner(e, owner)
+	}
 
 	//log.Debug("devMars", "oe", devMarsRF.Orbit)
 	log.Debug("ship", "oe", S.Orb[e], "points", S.Orb[e].PointsApprox(4))
-	
-
-	StartEngine()
-
-	// TODO: begin stationary relative top-level galactic grid
-	// TODO: implement hyperdrive in any direction; sector traversal triggering
-	//       star procgen
-	//
-	// TODO: and then - hyperdrive to a new star; triggering system procgen! :D  for E
-	//
-	//DevHyperdrive()
-
-	// TODO: this comes after system procgen
-	//DevShipOrbit()
+	return e
 }
 
 func StartEngine() {
 	systems := []System{
 		&Physics{},
-		//&Hyperdrive{},
+		&Hyperdrive{},
 	}
-	actionChan := make(chan Action, 10)
+	actionChan := make(chan *ActionRequest, 10)
 	subChan := make(chan *EntitySub, 10)
 	GE = &GameEngine{
 		systems:    systems,
@@ -204,7 +214,7 @@ func DevShipOrbit() {
 		Orbit:       sol.DefaultOrbit(),
 		Orientation: nil, // TODO
 	}
-	S.EntFrames[devMars.Entity] = devMarsRF
+	S.AddPlanet(devMars, devMarsRF)
 
 	S.EntFrames[e] = devMarsRF
 	S.Orb[e] = devMars.DefaultOrbit()
@@ -260,7 +270,7 @@ func ShipAndStation() {
 	S.EntsInFrames[localRF][stationEnt] = true
 	S.EntsInFrames[localRF][shipEnt] = true
 
-	actionChan := make(chan Action, 10)
+	actionChan := make(chan *ActionRequest, 10)
 	GE = &GameEngine{
 		systems:    []System{&Physics{}},
 		actionChan: actionChan,
@@ -395,9 +405,10 @@ func DevNewShip() Id {
 	S.Ori[e] = new(Q)
 
 	S.Rot[e] = &Rotational{new(V3), new(M3), new(M3), new(M4)}
-	shipIC := InertiaTensorCuboid(m0, 10, 10, 10)
-	shipIC.Inverse()
-	S.Rot[e].IITB = shipIC
+	S.Rot[e].IITB = shipInertiaTensor(m0)
+	S.CMG[e] = &CMG{new(V3)}
+	FitEngine(e, &Kestrel{})
+	FitJumpDrive(e, &Courier{})
 
 	fgs := make([]ForceGen, 0)
 	S.ForceGens[e] = fgs
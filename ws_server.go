@@ -21,6 +21,7 @@ import (
 	"time"
 	//"errors"
 	//"encoding/binary"
+	"encoding/json"
 	"net/http"
 	
 	"github.com/gorilla/websocket"
@@ -30,6 +31,8 @@ import (
 const (
 	// note: localhost:8081 as addr string works with raw TCP but not with HTTP
 	host = ":8081"
+
+	actionResultsBufferSize = 32
 )
 
 var upgrader = websocket.Upgrader{}
@@ -55,15 +58,34 @@ func httpHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// setup MessageBus sub to engine loop
+	// action results are routed back to this conn
+	results := make(chan *ActionResult, actionResultsBufferSize)
+	done := make(chan struct{})
+	defer close(done)
+
+	// setup MessageBus sub to engine loop, dropped when the conn closes.
+	// The goroutine below is the only writer of data messages to the conn.
+	ch := S.MsgBus.Subscribe()
+	defer S.MsgBus.Unsubscribe(ch)
 	go func() {
-		ch := S.MsgBus.Subscribe()
 		for {
-			stateJSON := <-ch
-			err = c.WriteMessage(websocket.BinaryMessage, stateJSON)
+			var msg []byte
+			var err error
+			select {
+			case <-done:
+				return
+			case msg = <-ch:
+			case r := <-results:
+				msg, err = json.Marshal(r)
+				if err != nil {
+					log.Error("marshal action result", "err", err)
+					continue
+				}
+			}
+			err = c.WriteMessage(websocket.BinaryMessage, msg)
 			if err != nil {
 				log.Error("write err:", "err", err)
-				break
+				return
 			}
 		}
 	}()
@@ -77,19 +99,17 @@ func httpHandler(w http.ResponseWriter, r *http.Request) {
 		}
 		log.Info("recv: ", "msg", msg)
 		
-		// handle action
-		err = HandleMsg(msg)
+		// handle action; rejections are reported on results and do not
+		// close the conn
+		err = HandleMsg(msg, results)
 		if err != nil {
-			log.Error("HandleMsg", "err", err)
-			log.Info("Closing websocket conn")
-			WriteControlClose(c, websocket.CloseInternalServerErr, err.Error())
-			return
+			log.Info("HandleMsg", "err", err)
 		}
 	}
 }
 
-func HandleMsg(msg []byte) error {
-	return nil
+func HandleMsg(msg []byte, results chan<- *ActionResult) error {
+	return HandleAction(msg, results)
 }
 
 func WriteControlClose(c *websocket.Conn, closeCode int, str string) error {
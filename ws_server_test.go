@@ -0,0 +1,65 @@
+/*  Copyright 2019 The tesseract Authors
+
+    This file is part of tesseract.
+
+    tesseract is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Affero General Public License as
+    published by the Free Software Foundation, either version 3 of the
+    License, or (at your option) any later version.
+
+    tesseract is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Affero General Public License for more details.
+
+    You should have received a copy of the GNU Affero General Public License
+    along with this program.  If not, see <https://www.gnu.org/licenses/>.
+*/
+package tesseract
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func msgBusSubscribers(mb *MessageBus) int {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	return len(mb.channels)
+}
+
+func TestWebSocketUnsubscribes(t *testing.T) {
+	ResetState()
+	srv := httptest.NewServer(http.HandlerFunc(httpHandler))
+	defer srv.Close()
+
+	waitSubscribers := func(n int) {
+		t.Helper()
+		for deadline := time.Now().Add(2 * time.Second); msgBusSubscribers(S.MsgBus) != n; {
+			if time.Now().After(deadline) {
+				t.Fatalf("got %d subscribers want %d", msgBusSubscribers(S.MsgBus), n)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	dialer := websocket.Dialer{Subprotocols: []string{"client0.argonavis.io"}}
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	for i := 0; i < 3; i++ {
+		c, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitSubscribers(1)
+		c.Close()
+		waitSubscribers(0)
+	}
+
+	// posting to no subscribers is fine
+	S.MsgBus.Post([]byte("{}"))
+}